@@ -0,0 +1,278 @@
+package main
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// requireAdmin ตรวจ token ของ admin endpoint (Authorization: Bearer <ADMIN_TOKEN> หรือ X-Admin-Token)
+// ถ้ายังไม่ได้ตั้งค่า ADMIN_TOKEN จะปิด admin endpoint ทั้งหมด
+func requireAdmin(w http.ResponseWriter, r *http.Request) bool {
+	if cfg.AdminToken == "" {
+		writeJSON(w, http.StatusForbidden, map[string]string{"error": "ยังไม่ได้ตั้งค่า ADMIN_TOKEN"})
+		return false
+	}
+
+	token := r.Header.Get("X-Admin-Token")
+	if auth := r.Header.Get("Authorization"); strings.HasPrefix(auth, "Bearer ") {
+		token = strings.TrimPrefix(auth, "Bearer ")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(cfg.AdminToken)) != 1 {
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "admin token ไม่ถูกต้อง"})
+		return false
+	}
+	return true
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// expansionCacheHandler ดู (GET) หรือลบ (DELETE, ?query= เฉพาะคำค้นหา) รายการใน expansion cache
+func expansionCacheHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORSSimple(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		stats, entries := expansionCache.snapshot()
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"stats":   stats,
+			"entries": entries,
+		})
+
+	case "DELETE":
+		query := r.URL.Query().Get("query")
+		removed := expansionCache.purge(query)
+		if err := expansionCache.save(); err != nil {
+			log.Printf("⚠️  บันทึก expansion cache ไม่สำเร็จ: %v", err)
+		}
+		log.Printf("🧹 ลบ expansion cache %d รายการ (query=%q)", removed, query)
+		writeJSON(w, http.StatusOK, map[string]int{"removed": removed})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// synonymRequest เพิ่ม/ลบกลุ่มคำพ้อง (shopid ว่าง = พจนานุกรมกลางของทุก shop)
+type synonymRequest struct {
+	ShopID string   `json:"shopid"`
+	Terms  []string `json:"terms"`
+}
+
+// synonymsHandler จัดการพจนานุกรมคำพ้อง: GET ?shopid= ดูกลุ่มคำ, POST เพิ่มกลุ่ม, DELETE ?shopid=&term= ลบกลุ่มที่มีคำนั้น
+func synonymsHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORSSimple(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	shopID := r.URL.Query().Get("shopid")
+	var req synonymRequest
+	if r.Method == "POST" {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "รูปแบบ JSON ไม่ถูกต้อง"})
+			return
+		}
+		shopID = req.ShopID
+	}
+	if shopID != "" && !shopIDPattern.MatchString(shopID) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "shopid ไม่ถูกต้อง: " + shopID})
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"shopid": shopID,
+			"groups": synonymDict.groups(shopID),
+		})
+
+	case "POST":
+		if err := synonymDict.addGroup(shopID, req.Terms); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Printf("📖 เพิ่มกลุ่มคำพ้อง (shopid=%q): %v", shopID, req.Terms)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"shopid": shopID,
+			"groups": synonymDict.groups(shopID),
+		})
+
+	case "DELETE":
+		term := r.URL.Query().Get("term")
+		if term == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "ต้องระบุ term"})
+			return
+		}
+		removed, err := synonymDict.removeTerm(shopID, term)
+		if err != nil && !os.IsNotExist(err) {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Printf("📖 ลบกลุ่มคำพ้อง %d กลุ่ม (shopid=%q, term=%q)", removed, shopID, term)
+		writeJSON(w, http.StatusOK, map[string]int{"removed": removed})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// dictionaryRequest คำที่จะเพิ่มในพจนานุกรมตัดคำ (shopid ว่าง = ใช้ทุก shop)
+type dictionaryRequest struct {
+	ShopID string   `json:"shopid"`
+	Words  []string `json:"words"`
+}
+
+// dictionaryHandler ดู (GET ?shopid=), เพิ่ม (POST) หรือลบ (DELETE ?shopid=&word=) คำศัพท์ตัดคำ
+// หลังแก้ไขจะ index เอกสารของ shop ที่ได้รับผลใหม่ทันที
+func dictionaryHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORSSimple(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+
+	shopID := r.URL.Query().Get("shopid")
+	var req dictionaryRequest
+	if r.Method == "POST" {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "รูปแบบ JSON ไม่ถูกต้อง"})
+			return
+		}
+		shopID = req.ShopID
+	}
+	if shopID != "" && !shopIDPattern.MatchString(shopID) {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": "shopid ไม่ถูกต้อง: " + shopID})
+		return
+	}
+
+	switch r.Method {
+	case "GET":
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"shopid": shopID,
+			"words":  segmentDict.words(shopID),
+		})
+
+	case "POST":
+		added, err := segmentDict.addWords(shopID, req.Words)
+		if err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Printf("✂️  เพิ่มคำศัพท์ตัดคำ (shopid=%q): %v", shopID, added)
+		writeJSON(w, http.StatusOK, map[string]interface{}{
+			"shopid":    shopID,
+			"added":     added,
+			"reindexed": reindexAfterDictionaryChange(len(added) > 0),
+		})
+
+	case "DELETE":
+		word := r.URL.Query().Get("word")
+		if word == "" {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": "ต้องระบุ word"})
+			return
+		}
+		removed, err := segmentDict.removeWord(shopID, word)
+		if err != nil && !os.IsNotExist(err) {
+			writeJSON(w, http.StatusInternalServerError, map[string]string{"error": err.Error()})
+			return
+		}
+		log.Printf("✂️  ลบคำศัพท์ตัดคำ %d คำ (shopid=%q, word=%q)", removed, shopID, word)
+		writeJSON(w, http.StatusOK, map[string]int{
+			"removed":   removed,
+			"reindexed": reindexAfterDictionaryChange(removed > 0),
+		})
+
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// reindexAfterDictionaryChange ตัดคำเอกสารที่คำศัพท์เปลี่ยนใหม่ แล้วบันทึก index คืนจำนวนไฟล์ที่ index ใหม่
+func reindexAfterDictionaryChange(changed bool) int {
+	if !changed {
+		return 0
+	}
+	reindexed, err := docIndex.refresh()
+	if err != nil {
+		log.Printf("⚠️  index ใหม่หลังแก้คำศัพท์ไม่สำเร็จ: %v", err)
+	}
+	if reindexed > 0 {
+		if err := docIndex.save(); err != nil {
+			log.Printf("⚠️  บันทึก index ไม่สำเร็จ: %v", err)
+		}
+	}
+	log.Printf("✂️  index ใหม่ %d ไฟล์หลังแก้คำศัพท์ตัดคำ", reindexed)
+	return reindexed
+}
+
+// segmentToken คำที่ตัดได้ใน preview (Known = อยู่ในพจนานุกรม, false มักเป็นเศษคำที่ควรเพิ่มคำศัพท์)
+type segmentToken struct {
+	Term  string `json:"term"`
+	Known bool   `json:"known"`
+}
+
+// segmentPreviewHandler แสดงผลการตัดคำของข้อความด้วยพจนานุกรมของ shop (GET ?text=&shopid=&tokenizer=)
+func segmentPreviewHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORSSimple(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+	if !requireAdmin(w, r) {
+		return
+	}
+	if r.Method != "GET" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	text := r.URL.Query().Get("text")
+	shopID, err := resolveShopID(r.URL.Query().Get("shopid"))
+	if err != nil {
+		writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+		return
+	}
+
+	tokenizer := queryTokenizer
+	if name := r.URL.Query().Get("tokenizer"); name != "" {
+		if tokenizer, err = newTokenizer(name); err != nil {
+			writeJSON(w, http.StatusBadRequest, map[string]string{"error": err.Error()})
+			return
+		}
+	}
+
+	normalized := normalizeText(text)
+	var tokens []segmentToken
+	for _, tok := range tokenizeLine(tokenizer, shopID, normalized) {
+		tokens = append(tokens, segmentToken{
+			Term:  tok.Term,
+			Known: !hasThaiCharacters(tok.Term) || segmentDict.isKnown(shopID, tok.Term),
+		})
+	}
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"shopid":     shopID,
+		"text":       text,
+		"normalized": normalized,
+		"segmenter":  segmenterName(),
+		"tokenizer":  tokenizer.Name(),
+		"tokens":     tokens,
+	})
+}
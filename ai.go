@@ -1,13 +1,30 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log"
 	"net/http"
+	"strings"
+	"time"
 )
 
+// LLMProvider ผู้ให้บริการ LLM สำหรับสรุปผลการค้นหา
+type LLMProvider interface {
+	Name() string
+	Generate(ctx context.Context, prompt string) (string, error)
+}
+
+// StreamingLLMProvider provider ที่ส่งคำตอบกลับทีละส่วนได้ (onToken ถูกเรียกทุกครั้งที่ได้ข้อความเพิ่ม)
+type StreamingLLMProvider interface {
+	LLMProvider
+	GenerateStream(ctx context.Context, prompt string, onToken func(string) error) error
+}
+
 type GeminiRequest struct {
 	Contents []GeminiContent `json:"contents"`
 }
@@ -28,35 +45,52 @@ type GeminiCandidate struct {
 	Content GeminiContent `json:"content"`
 }
 
-type DeepSeekRequest struct {
-	Model    string            `json:"model"`
-	Messages []DeepSeekMessage `json:"messages"`
+// OpenAIChatRequest ใช้ได้กับทุก endpoint ที่รองรับ OpenAI chat completions (รวม DeepSeek)
+type OpenAIChatRequest struct {
+	Model    string              `json:"model"`
+	Messages []OpenAIChatMessage `json:"messages"`
+	Stream   bool                `json:"stream,omitempty"`
 }
 
-type DeepSeekMessage struct {
+type OpenAIChatMessage struct {
 	Role    string `json:"role"`
 	Content string `json:"content"`
 }
 
-type DeepSeekResponse struct {
-	Choices []DeepSeekChoice `json:"choices"`
+type OpenAIChatResponse struct {
+	Choices []OpenAIChatChoice `json:"choices"`
 }
 
-type DeepSeekChoice struct {
-	Message DeepSeekMessage `json:"message"`
+type OpenAIChatChoice struct {
+	Message OpenAIChatMessage `json:"message"`
+	Delta   OpenAIChatMessage `json:"delta"` // ใช้ตอน stream
 }
 
-func summarizeWithGeminiText(apiKey, context, query string) (string, error) {
-	if apiKey == "" {
-		return "", fmt.Errorf("GEMINI_API_KEY not configured")
-	}
+// OllamaChatRequest for Ollama /api/chat
+type OllamaChatRequest struct {
+	Model     string              `json:"model"`
+	Messages  []OpenAIChatMessage `json:"messages"` // รูปแบบ role/content เดียวกับ OpenAI
+	Stream    bool                `json:"stream"`
+	Options   map[string]float64  `json:"options,omitempty"`
+	KeepAlive string              `json:"keep_alive,omitempty"`
+}
 
-	prompt := fmt.Sprintf(`???????????????????????????????: %s
+// OllamaChatResponse from Ollama /api/chat (ตอน stream จะได้ทีละบรรทัดจนกว่า done)
+type OllamaChatResponse struct {
+	Message OpenAIChatMessage `json:"message"`
+	Done    bool              `json:"done"`
+}
 
-%s
+// geminiProvider เรียก Google Gemini generateContent
+type geminiProvider struct {
+	apiKey  string
+	baseURL string
+	model   string
+}
 
-????????????? ????? ????????:`, query, context)
+func (p *geminiProvider) Name() string { return "gemini" }
 
+func (p *geminiProvider) Generate(ctx context.Context, prompt string) (string, error) {
 	reqBody := GeminiRequest{
 		Contents: []GeminiContent{
 			{
@@ -67,89 +101,278 @@ func summarizeWithGeminiText(apiKey, context, query string) (string, error) {
 		},
 	}
 
-	jsonData, err := json.Marshal(reqBody)
-	if err != nil {
-		return "", err
+	url := fmt.Sprintf("%s/models/%s:generateContent?key=%s", strings.TrimRight(p.baseURL, "/"), p.model, p.apiKey)
+
+	var geminiResp GeminiResponse
+	if err := postJSON(ctx, url, nil, reqBody, &geminiResp); err != nil {
+		return "", fmt.Errorf("gemini API error: %w", err)
 	}
 
-	url := fmt.Sprintf("https://generativelanguage.googleapis.com/v1beta/models/gemini-pro:generateContent?key=%s", apiKey)
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
-	if err != nil {
-		return "", err
+	if len(geminiResp.Candidates) > 0 && len(geminiResp.Candidates[0].Content.Parts) > 0 {
+		return geminiResp.Candidates[0].Content.Parts[0].Text, nil
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("gemini API error: %s", string(body))
+	return "", fmt.Errorf("no response from Gemini")
+}
+
+// openAIProvider เรียก endpoint ที่รองรับ OpenAI chat completions (DeepSeek, OpenAI, vLLM, LM Studio ฯลฯ)
+type openAIProvider struct {
+	name    string
+	apiKey  string
+	baseURL string
+	model   string
+}
+
+func (p *openAIProvider) Name() string { return p.name }
+
+func (p *openAIProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	var chatResp OpenAIChatResponse
+	if err := postJSON(ctx, p.url(), p.headers(), p.request(prompt, false), &chatResp); err != nil {
+		return "", fmt.Errorf("%s API error: %w", p.name, err)
 	}
 
-	var geminiResp GeminiResponse
-	if err := json.NewDecoder(resp.Body).Decode(&geminiResp); err != nil {
-		return "", err
+	if len(chatResp.Choices) > 0 {
+		return chatResp.Choices[0].Message.Content, nil
 	}
 
-	if len(geminiResp.Candidates) > 0 && len(geminiResp.Candidates[0].Content.Parts) > 0 {
-		return geminiResp.Candidates[0].Content.Parts[0].Text, nil
+	return "", fmt.Errorf("no response from %s", p.name)
+}
+
+// GenerateStream อ่าน server-sent events ("data: {...}" จนถึง "data: [DONE]")
+func (p *openAIProvider) GenerateStream(ctx context.Context, prompt string, onToken func(string) error) error {
+	body, err := postStream(ctx, p.url(), p.headers(), p.request(prompt, true))
+	if err != nil {
+		return fmt.Errorf("%s API error: %w", p.name, err)
+	}
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		data, ok := strings.CutPrefix(scanner.Text(), "data:")
+		if !ok {
+			continue
+		}
+		data = strings.TrimSpace(data)
+		if data == "[DONE]" {
+			return nil
+		}
+
+		var chunk OpenAIChatResponse
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			return fmt.Errorf("%s stream decode: %w", p.name, err)
+		}
+		if len(chunk.Choices) > 0 && chunk.Choices[0].Delta.Content != "" {
+			if err := onToken(chunk.Choices[0].Delta.Content); err != nil {
+				return err
+			}
+		}
 	}
+	return scanner.Err()
+}
 
-	return "", fmt.Errorf("no response from Gemini")
+func (p *openAIProvider) url() string {
+	return strings.TrimRight(p.baseURL, "/") + "/chat/completions"
 }
 
-func summarizeWithDeepSeekText(apiKey, context, query string) (string, error) {
-	if apiKey == "" {
-		return "", fmt.Errorf("DEEPSEEK_API_KEY not configured")
+func (p *openAIProvider) headers() map[string]string {
+	headers := map[string]string{}
+	if p.apiKey != "" {
+		headers["Authorization"] = "Bearer " + p.apiKey
 	}
+	return headers
+}
 
-	prompt := fmt.Sprintf(`????????????????????????????????????????????: %s
+func (p *openAIProvider) request(prompt string, stream bool) OpenAIChatRequest {
+	return OpenAIChatRequest{
+		Model: p.model,
+		Messages: []OpenAIChatMessage{
+			{
+				Role:    "user",
+				Content: prompt,
+			},
+		},
+		Stream: stream,
+	}
+}
 
-%s
+// ollamaProvider เรียก Ollama /api/chat ในเครื่อง (ใช้งานได้โดยไม่ต้องออกอินเทอร์เน็ต)
+type ollamaProvider struct {
+	host        string
+	model       string
+	numCtx      int
+	temperature float64
+	keepAlive   string
+}
+
+func (p *ollamaProvider) Name() string { return "ollama" }
+
+func (p *ollamaProvider) Generate(ctx context.Context, prompt string) (string, error) {
+	var ollamaResp OllamaChatResponse
+	if err := postJSON(ctx, p.url(), nil, p.request(prompt, false), &ollamaResp); err != nil {
+		return "", fmt.Errorf("ollama API error: %w", err)
+	}
+
+	if strings.TrimSpace(ollamaResp.Message.Content) == "" {
+		return "", fmt.Errorf("no response from Ollama")
+	}
+	return ollamaResp.Message.Content, nil
+}
+
+// GenerateStream อ่าน JSON ทีละบรรทัดจาก Ollama จนกว่าจะ done
+func (p *ollamaProvider) GenerateStream(ctx context.Context, prompt string, onToken func(string) error) error {
+	body, err := postStream(ctx, p.url(), nil, p.request(prompt, true))
+	if err != nil {
+		return fmt.Errorf("ollama API error: %w", err)
+	}
+	defer body.Close()
+
+	decoder := json.NewDecoder(body)
+	for {
+		var chunk OllamaChatResponse
+		if err := decoder.Decode(&chunk); err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return fmt.Errorf("ollama stream decode: %w", err)
+		}
+		if chunk.Message.Content != "" {
+			if err := onToken(chunk.Message.Content); err != nil {
+				return err
+			}
+		}
+		if chunk.Done {
+			return nil
+		}
+	}
+}
 
-??????????????? ?????? ?????????:`, query, context)
+func (p *ollamaProvider) url() string {
+	return strings.TrimRight(p.host, "/") + "/api/chat"
+}
 
-	reqBody := DeepSeekRequest{
-		Model: "deepseek-chat",
-		Messages: []DeepSeekMessage{
+func (p *ollamaProvider) request(prompt string, stream bool) OllamaChatRequest {
+	return OllamaChatRequest{
+		Model: p.model,
+		Messages: []OpenAIChatMessage{
 			{
 				Role:    "user",
 				Content: prompt,
 			},
 		},
+		Stream: stream,
+		Options: map[string]float64{
+			"num_ctx":     float64(p.numCtx),
+			"temperature": p.temperature,
+		},
+		KeepAlive: p.keepAlive,
 	}
+}
 
-	jsonData, err := json.Marshal(reqBody)
+// apiHTTPClient ใช้เรียก LLM และ embedding ทุกตัวแทน http.DefaultClient ที่ไม่มี timeout
+// Timeout ครอบทั้ง request รวมการอ่าน stream จึงกันกรณีผู้เรียกส่ง ctx ที่ไม่มี deadline มา (ตั้งค่าใน main)
+var apiHTTPClient = &http.Client{Timeout: 60 * time.Second}
+
+// postJSON ส่ง request แบบ JSON แล้ว decode response ลง out
+func postJSON(ctx context.Context, url string, headers map[string]string, body, out interface{}) error {
+	respBody, err := postStream(ctx, url, headers, body)
 	if err != nil {
-		return "", err
+		return err
 	}
+	defer respBody.Close()
 
-	req, err := http.NewRequest("POST", "https://api.deepseek.com/v1/chat/completions", bytes.NewBuffer(jsonData))
+	return json.NewDecoder(respBody).Decode(out)
+}
+
+// postStream ส่ง request แบบ JSON แล้วคืน body ให้ผู้เรียกอ่านเอง (ผู้เรียกต้อง Close)
+func postStream(ctx context.Context, url string, headers map[string]string, body interface{}) (io.ReadCloser, error) {
+	jsonData, err := json.Marshal(body)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
+	req, err := http.NewRequestWithContext(ctx, "POST", url, bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
 	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+apiKey)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	resp, err := apiHTTPClient.Do(req)
 	if err != nil {
-		return "", err
+		return nil, err
 	}
-	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		body, _ := io.ReadAll(resp.Body)
-		return "", fmt.Errorf("deepseek API error: %s", string(body))
+		respBody, _ := io.ReadAll(resp.Body)
+		resp.Body.Close()
+		return nil, fmt.Errorf("status %d: %s", resp.StatusCode, string(respBody))
 	}
 
-	var deepseekResp DeepSeekResponse
-	if err := json.NewDecoder(resp.Body).Decode(&deepseekResp); err != nil {
-		return "", err
-	}
+	return resp.Body, nil
+}
 
-	if len(deepseekResp.Choices) > 0 {
-		return deepseekResp.Choices[0].Message.Content, nil
+// newLLMProviders สร้างลำดับ provider ตาม Config.LLMProviders (ข้ามตัวที่ตั้งค่าไม่ครบ)
+// ใส่ "ollama" ไว้ตัวแรกเพื่อใช้ LLM ในเครื่องก่อน หรือไว้ท้ายสุดเป็น fallback เมื่อออกอินเทอร์เน็ตไม่ได้
+func newLLMProviders(cfg *Config) []LLMProvider {
+	var providers []LLMProvider
+
+	for _, name := range cfg.LLMProviders {
+		name = strings.ToLower(strings.TrimSpace(name))
+
+		// โหมด offline ใช้เฉพาะ provider ที่รันในเครื่อง/ในเครือข่ายภายใน
+		if cfg.OfflineMode && name != "ollama" && name != "openai" {
+			log.Printf("⚠️  ข้าม %s: OFFLINE_MODE เปิดอยู่", name)
+			continue
+		}
+
+		switch name {
+		case "gemini":
+			if cfg.GeminiAPIKey == "" {
+				log.Printf("⚠️  ข้าม gemini: ไม่ได้ตั้ง GEMINI_API_KEY")
+				continue
+			}
+			providers = append(providers, &geminiProvider{apiKey: cfg.GeminiAPIKey, baseURL: cfg.GeminiBaseURL, model: cfg.GeminiModel})
+		case "deepseek":
+			if cfg.DeepSeekAPIKey == "" {
+				log.Printf("⚠️  ข้าม deepseek: ไม่ได้ตั้ง DEEPSEEK_API_KEY")
+				continue
+			}
+			providers = append(providers, &openAIProvider{name: "deepseek", apiKey: cfg.DeepSeekAPIKey, baseURL: cfg.DeepSeekBaseURL, model: cfg.DeepSeekModel})
+		case "openai":
+			if cfg.OpenAIBaseURL == "" {
+				log.Printf("⚠️  ข้าม openai: ไม่ได้ตั้ง OPENAI_BASE_URL")
+				continue
+			}
+			providers = append(providers, &openAIProvider{name: "openai", apiKey: cfg.OpenAIAPIKey, baseURL: cfg.OpenAIBaseURL, model: cfg.OpenAIModel})
+		case "ollama":
+			providers = append(providers, &ollamaProvider{
+				host:        cfg.OllamaHost,
+				model:       cfg.OllamaLLMModel,
+				numCtx:      cfg.OllamaLLMNumCtx,
+				temperature: cfg.OllamaLLMTemperature,
+				keepAlive:   cfg.OllamaLLMKeepAlive,
+			})
+		case "":
+		default:
+			log.Printf("⚠️  ไม่รู้จัก LLM provider: %s", name)
+		}
 	}
 
-	return "", fmt.Errorf("no response from DeepSeek")
+	return providers
+}
+
+// buildSummaryPrompt สร้าง prompt สำหรับสรุปผลการค้นหา
+func buildSummaryPrompt(query, context string) string {
+	return fmt.Sprintf(`คุณเป็นผู้ช่วยตอบคำถามจากเอกสารของบริษัท
+
+คำถามของผู้ใช้: %s
+
+ข้อมูลที่ค้นพบจากเอกสาร:
+%s
+
+สรุปคำตอบเป็นภาษาไทย กระชับ ตรงประเด็น โดยใช้เฉพาะข้อมูลข้างต้น และระบุแหล่งที่มา (ไฟล์, บรรทัด)
+ถ้าข้อมูลไม่พอให้ตอบว่าไม่พบข้อมูล:`, query, context)
 }
@@ -0,0 +1,255 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// BuildRequest สำหรับสร้าง vector ของเอกสาร 1 ไฟล์
+type BuildRequest struct {
+	ShopID          string `json:"shopid"`
+	Filename        string `json:"filename"`
+	EmailUserCreate string `json:"emailusercreate,omitempty"`
+}
+
+// BuildResponse ผลการสร้าง vector
+type BuildResponse struct {
+	ShopID   string `json:"shopid"`
+	Filename string `json:"filename"`
+	Chunks   int    `json:"chunks"`
+	Duration string `json:"duration,omitempty"`
+	Error    string `json:"error,omitempty"`
+}
+
+// DocumentChunk ข้อความ 1 ส่วนของเอกสาร พร้อมช่วงบรรทัด (เริ่มที่ 1)
+type DocumentChunk struct {
+	Index     int
+	Content   string
+	StartLine int
+	EndLine   int
+	Embedding []float64
+}
+
+func buildHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORSSimple(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req BuildRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeBuildResponse(w, http.StatusBadRequest, BuildResponse{Error: "รูปแบบ JSON ไม่ถูกต้อง"})
+		return
+	}
+
+	if req.ShopID == "" || req.Filename == "" {
+		writeBuildResponse(w, http.StatusBadRequest, BuildResponse{ShopID: req.ShopID, Filename: req.Filename, Error: "ต้องระบุ shopid และ filename"})
+		return
+	}
+
+	if _, err := resolveShopID(req.ShopID); err != nil {
+		writeBuildResponse(w, http.StatusBadRequest, BuildResponse{ShopID: req.ShopID, Filename: req.Filename, Error: err.Error()})
+		return
+	}
+
+	if db == nil {
+		writeBuildResponse(w, http.StatusServiceUnavailable, BuildResponse{ShopID: req.ShopID, Filename: req.Filename, Error: "ยังไม่ได้เชื่อมต่อฐานข้อมูล"})
+		return
+	}
+
+	path, err := shopDocFilePath(req.ShopID, req.Filename)
+	if err != nil {
+		writeBuildResponse(w, http.StatusBadRequest, BuildResponse{ShopID: req.ShopID, Filename: req.Filename, Error: err.Error()})
+		return
+	}
+
+	start := time.Now()
+	log.Printf("🏗️  สร้าง vector: shopid=%s filename=%s", req.ShopID, req.Filename)
+
+	chunks, err := buildDocumentVectors(r.Context(), req, path)
+	if err != nil {
+		log.Printf("❌ สร้าง vector ไม่สำเร็จ: %v", err)
+		writeBuildResponse(w, http.StatusInternalServerError, BuildResponse{ShopID: req.ShopID, Filename: req.Filename, Error: err.Error()})
+		return
+	}
+
+	log.Printf("✅ สร้าง vector สำเร็จ %d chunks (%v)", chunks, time.Since(start))
+	writeBuildResponse(w, http.StatusOK, BuildResponse{
+		ShopID:   req.ShopID,
+		Filename: req.Filename,
+		Chunks:   chunks,
+		Duration: time.Since(start).String(),
+	})
+}
+
+func writeBuildResponse(w http.ResponseWriter, status int, response BuildResponse) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// buildDocumentVectors ตัด chunk → สร้าง embeddings พร้อมกัน → แทนที่ข้อมูลเดิมใน documents
+func buildDocumentVectors(ctx context.Context, req BuildRequest, path string) (int, error) {
+	lines, err := readLines(path)
+	if err != nil {
+		return 0, fmt.Errorf("อ่านไฟล์ไม่สำเร็จ: %w", err)
+	}
+
+	chunks := chunkDocument(lines, cfg.ChunkSize)
+	if len(chunks) == 0 {
+		return 0, fmt.Errorf("ไฟล์ %s ไม่มีเนื้อหา", req.Filename)
+	}
+	log.Printf("   ✂️  ตัดได้ %d chunks", len(chunks))
+
+	if err := embedChunks(ctx, chunks, cfg.BuildWorkers); err != nil {
+		return 0, err
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	// ลบข้อมูลเก่าของ (shopid, filename) ก่อน
+	result, err := tx.ExecContext(ctx, `DELETE FROM documents WHERE shopid = $1 AND filename = $2`, req.ShopID, req.Filename)
+	if err != nil {
+		return 0, fmt.Errorf("ลบข้อมูลเก่าไม่สำเร็จ: %w", err)
+	}
+	if deleted, _ := result.RowsAffected(); deleted > 0 {
+		log.Printf("   🗑️  ลบข้อมูลเก่า %d แถว", deleted)
+	}
+
+	stmt, err := tx.PrepareContext(ctx, `INSERT INTO documents (shopid, filename, content, embedding, metadata) VALUES ($1, $2, $3, $4::vector, $5)`)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for _, chunk := range chunks {
+		metadata, _ := json.Marshal(map[string]int{
+			"chunk_index": chunk.Index,
+			"start_line":  chunk.StartLine,
+			"end_line":    chunk.EndLine,
+		})
+		if _, err := stmt.ExecContext(ctx, req.ShopID, req.Filename, chunk.Content, vectorLiteral(chunk.Embedding), string(metadata)); err != nil {
+			return 0, fmt.Errorf("บันทึก chunk %d ไม่สำเร็จ: %w", chunk.Index, err)
+		}
+	}
+
+	// บันทึกประวัติการสร้าง embeddings
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO shopidfilename (shopid, filename, emailusercreate)
+		VALUES ($1, $2, NULLIF($3, ''))
+		ON CONFLICT (shopid, filename)
+		DO UPDATE SET emailusercreate = COALESCE(EXCLUDED.emailusercreate, shopidfilename.emailusercreate),
+		              updatedate = CURRENT_TIMESTAMP`,
+		req.ShopID, req.Filename, req.EmailUserCreate)
+	if err != nil {
+		return 0, fmt.Errorf("บันทึก shopidfilename ไม่สำเร็จ: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	return len(chunks), nil
+}
+
+// embedChunks สร้าง embedding ของทุก chunk ด้วย worker หลายตัวพร้อมกัน
+func embedChunks(ctx context.Context, chunks []DocumentChunk, workers int) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	var once sync.Once
+	var firstErr error
+
+	for i := 0; i < min(max(workers, 1), len(chunks)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				embedding, err := createEmbedding(ctx, cfg, chunks[j].Content)
+				if err != nil {
+					once.Do(func() {
+						firstErr = fmt.Errorf("สร้าง embedding chunk %d ไม่สำเร็จ: %w", chunks[j].Index, err)
+						cancel()
+					})
+					continue
+				}
+				chunks[j].Embedding = embedding
+			}
+		}()
+	}
+
+	for i := range chunks {
+		select {
+		case jobs <- i:
+		case <-ctx.Done():
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	return firstErr
+}
+
+// chunkDocument ตัดเอกสารเป็น chunk ตามย่อหน้า โดยแต่ละ chunk ยาวไม่เกิน chunkSize ตัวอักษร
+// (ย่อหน้าที่ยาวเกินจะถูกตัดแยกตามบรรทัด)
+func chunkDocument(lines []string, chunkSize int) []DocumentChunk {
+	var chunks []DocumentChunk
+	var current []string
+	startLine, size := 0, 0
+
+	flush := func(endLine int) {
+		content := strings.TrimSpace(strings.Join(current, "\n"))
+		if content != "" {
+			chunks = append(chunks, DocumentChunk{
+				Index:     len(chunks),
+				Content:   content,
+				StartLine: startLine,
+				EndLine:   endLine,
+			})
+		}
+		current = nil
+		size = 0
+	}
+
+	for i, line := range lines {
+		lineSize := utf8.RuneCountInString(line)
+
+		// เริ่ม chunk ใหม่เมื่อเกินขนาด โดยพยายามตัดที่บรรทัดว่าง
+		if len(current) > 0 && size+lineSize > chunkSize {
+			flush(i)
+		}
+		if len(current) == 0 {
+			if strings.TrimSpace(line) == "" {
+				continue
+			}
+			startLine = i + 1
+		}
+
+		current = append(current, line)
+		size += lineSize
+
+		if strings.TrimSpace(line) == "" && size >= chunkSize/2 {
+			flush(i + 1)
+		}
+	}
+	flush(len(lines))
+
+	return chunks
+}
@@ -3,6 +3,8 @@ package main
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -13,10 +15,72 @@ type Config struct {
 	DBUser         string
 	DBPassword     string
 	DBName         string
+	DBSSLMode      string
 	OllamaHost     string
 	OllamaModel    string
 	GeminiAPIKey   string
 	DeepSeekAPIKey string
+	// LLM สำหรับสรุปผล: ลำดับ fallback และ endpoint ของแต่ละ provider
+	LLMProviders         []string
+	LLMTimeout           int // วินาทีต่อ provider
+	GeminiBaseURL        string
+	GeminiModel          string
+	DeepSeekBaseURL      string
+	DeepSeekModel        string
+	OpenAIBaseURL        string
+	OpenAIAPIKey         string
+	OpenAIModel          string
+	OllamaLLMModel       string
+	OllamaLLMNumCtx      int
+	OllamaLLMTemperature float64
+	OllamaLLMKeepAlive   string
+	OfflineMode          bool // ใช้เฉพาะ LLM ในเครื่อง (ollama, openai-compatible ภายใน)
+	DocPath              string
+	DefaultShopID        string // เจ้าของไฟล์ที่อยู่ชั้นบนสุดของ DocPath
+	AllowDefaultShopID   bool   // request ที่ไม่ระบุ shopid ใช้ DefaultShopID (ปิดไว้ = ต้องระบุทุกครั้ง)
+	IndexPath            string
+	IndexRefresh         int // วินาที
+	ContextMaxLines      int // block markdown ที่ยาวกว่านี้จะแสดงเฉพาะ ±ContextLines รอบบรรทัดที่เจอ
+	ContextLines         int
+	PassageMaxLines      int // passage ที่รวมจากหลายผลลัพธ์ยาวได้ไม่เกินนี้ (0 = ไม่จำกัด)
+	ChunkSize            int // จำนวนตัวอักษรต่อ chunk ตอนสร้าง vector
+	BuildWorkers         int
+	VectorLimit          int
+	VectorThreshold      float64
+	// น้ำหนักของแต่ละฝั่งใน hybrid search (reciprocal rank fusion)
+	HybridTextWeight   float64
+	HybridVectorWeight float64
+	// tag ที่ครอบคำค้นหาใน field "highlighted" ของผลลัพธ์
+	HighlightPreTag  string
+	HighlightPostTag string
+	DedupSimilarity  float64 // Jaccard similarity ขั้นต่ำที่ถือว่าเนื้อหาซ้ำ (0 = ไม่รวม)
+	DedupCandidates  int     // ตรวจเนื้อหาซ้ำเฉพาะผลลัพธ์อันดับต้นๆ กี่รายการ (เทียบทีละคู่ จึงต้องจำกัด)
+	// cache ผลการขยายคำค้นหา (0 รายการ = ปิด, path ว่าง = ไม่บันทึกลง disk)
+	ExpansionCacheSize int
+	ExpansionCacheTTL  int // วินาที
+	ExpansionCachePath string
+	AdminToken         string // token ของ /admin/* (ว่าง = ปิด)
+	// การขยายคำค้นหาด้วย LLM (prompt อยู่ใน PromptDir/expansion.tmpl หรือ PromptDir/<shopid>/expansion.tmpl)
+	ExpansionEnabled     bool
+	ExpansionModel       string
+	ExpansionMaxKeywords int
+	PromptDir            string
+	// พจนานุกรมคำพ้อง (SynonymDir/synonyms.tsv และ SynonymDir/<shopid>/synonyms.tsv)
+	SynonymDir     string
+	SynonymSkipLLM bool           // ไม่เรียก LLM เมื่อพจนานุกรมมีคำพ้องของคำค้นหาแล้ว
+	Fuzziness      fuzzinessLevel // จำนวนตัวที่สะกดผิดได้ (auto = ตามความยาวคำ, 0 = ปิด)
+	// พจนานุกรมตัดคำ: คำมาตรฐาน (SegmentBaseDict) + SegmentDictDir/words.txt + SegmentDictDir/<shopid>/words.txt
+	SegmentBaseDict string
+	SegmentDictDir  string
+	// tokenizer ภาษาไทยของแต่ละ field: mapkha, tcc, bigram, trigram, simple (ดู tokenizer.go)
+	ContentTokenizer string
+	HeadingTokenizer string
+	QueryTokenizer   string
+	// การกรองคำที่ได้จากการตัดคำ (ดู stopwords.go)
+	StopwordFile        string
+	MinKeywordLength    int     // จำนวนอักขระขั้นต่ำ
+	KeywordMaxLineRatio float64 // คำที่อยู่ในบรรทัดมากกว่าสัดส่วนนี้ถูกตัดทิ้ง
+	KeywordMinLines     int     // ใช้ KeywordMaxLineRatio เฉพาะ shop ที่มีบรรทัดอย่างน้อยเท่านี้
 }
 
 func loadConfig() *Config {
@@ -25,15 +89,68 @@ func loadConfig() *Config {
 	}
 
 	return &Config{
-		DBHost:         getEnv("DB_HOST", "localhost"),
-		DBPort:         getEnv("DB_PORT", "5432"),
-		DBUser:         getEnv("DB_USER", "postgres"),
-		DBPassword:     getEnv("DB_PASSWORD", ""),
-		DBName:         getEnv("DB_NAME", "testvector"),
-		OllamaHost:     getEnv("OLLAMA_HOST", "http://localhost:11434"),
-		OllamaModel:    getEnv("OLLAMA_MODEL", "bge-m3"),
-		GeminiAPIKey:   getEnv("GEMINI_API_KEY", ""),
-		DeepSeekAPIKey: getEnv("DEEPSEEK_API_KEY", ""),
+		DBHost:               getEnv("DB_HOST", "localhost"),
+		DBPort:               getEnv("DB_PORT", "5432"),
+		DBUser:               getEnv("DB_USER", "postgres"),
+		DBPassword:           getEnv("DB_PASSWORD", ""),
+		DBName:               getEnv("DB_NAME", "testvector"),
+		DBSSLMode:            getEnv("DB_SSLMODE", "disable"),
+		OllamaHost:           getEnv("OLLAMA_HOST", "http://localhost:11434"),
+		OllamaModel:          getEnv("OLLAMA_MODEL", "bge-m3"),
+		GeminiAPIKey:         getEnv("GEMINI_API_KEY", ""),
+		DeepSeekAPIKey:       getEnv("DEEPSEEK_API_KEY", ""),
+		LLMProviders:         getEnvList("LLM_PROVIDERS", []string{"gemini", "deepseek", "ollama"}),
+		LLMTimeout:           getEnvInt("LLM_TIMEOUT_SECONDS", 60),
+		GeminiBaseURL:        getEnv("GEMINI_BASE_URL", "https://generativelanguage.googleapis.com/v1beta"),
+		GeminiModel:          getEnv("GEMINI_MODEL", "gemini-pro"),
+		DeepSeekBaseURL:      getEnv("DEEPSEEK_BASE_URL", "https://api.deepseek.com/v1"),
+		DeepSeekModel:        getEnv("DEEPSEEK_MODEL", "deepseek-chat"),
+		OpenAIBaseURL:        getEnv("OPENAI_BASE_URL", ""),
+		OpenAIAPIKey:         getEnv("OPENAI_API_KEY", ""),
+		OpenAIModel:          getEnv("OPENAI_MODEL", "gpt-4o-mini"),
+		OllamaLLMModel:       getEnv("OLLAMA_LLM_MODEL", "llama3.2"),
+		OllamaLLMNumCtx:      getEnvInt("OLLAMA_LLM_NUM_CTX", 8192),
+		OllamaLLMTemperature: getEnvFloat("OLLAMA_LLM_TEMPERATURE", 0.2),
+		OllamaLLMKeepAlive:   getEnv("OLLAMA_LLM_KEEP_ALIVE", "10m"),
+		OfflineMode:          getEnvBool("OFFLINE_MODE", false),
+		DocPath:              getEnv("DOC_PATH", "./doc"),
+		DefaultShopID:        getEnv("DEFAULT_SHOP_ID", "shop001"),
+		AllowDefaultShopID:   getEnvBool("ALLOW_DEFAULT_SHOP_ID", false),
+		IndexPath:            getEnv("INDEX_PATH", "./data/index.gob"),
+		IndexRefresh:         getEnvInt("INDEX_REFRESH_SECONDS", 30),
+		ContextMaxLines:      getEnvInt("CONTEXT_MAX_LINES", 30),
+		ContextLines:         getEnvInt("CONTEXT_LINES", 3),
+		PassageMaxLines:      getEnvInt("PASSAGE_MAX_LINES", 60),
+		ChunkSize:            getEnvInt("CHUNK_SIZE", 600),
+		BuildWorkers:         getEnvInt("BUILD_WORKERS", 100),
+		VectorLimit:          getEnvInt("VECTOR_LIMIT", 5),
+		VectorThreshold:      getEnvFloat("VECTOR_THRESHOLD", 0.5),
+		HybridTextWeight:     getEnvFloat("HYBRID_TEXT_WEIGHT", 1.0),
+		HybridVectorWeight:   getEnvFloat("HYBRID_VECTOR_WEIGHT", 1.0),
+		HighlightPreTag:      getEnv("HIGHLIGHT_PRE_TAG", "<mark>"),
+		HighlightPostTag:     getEnv("HIGHLIGHT_POST_TAG", "</mark>"),
+		DedupSimilarity:      getEnvFloat("DEDUP_SIMILARITY", 0.9),
+		DedupCandidates:      getEnvInt("DEDUP_CANDIDATES", 200),
+		ExpansionCacheSize:   getEnvInt("EXPANSION_CACHE_SIZE", 1000),
+		ExpansionCacheTTL:    getEnvInt("EXPANSION_CACHE_TTL_SECONDS", 86400),
+		ExpansionCachePath:   getEnv("EXPANSION_CACHE_PATH", "./data/expansion_cache.json"),
+		AdminToken:           getEnv("ADMIN_TOKEN", ""),
+		ExpansionEnabled:     getEnvBool("EXPANSION_ENABLED", true),
+		ExpansionModel:       getEnv("EXPANSION_MODEL", "llama3.2"),
+		ExpansionMaxKeywords: getEnvInt("EXPANSION_MAX_KEYWORDS", 15),
+		PromptDir:            getEnv("PROMPT_DIR", "./prompts"),
+		SynonymDir:           getEnv("SYNONYM_DIR", "./dict"),
+		SynonymSkipLLM:       getEnvBool("SYNONYM_SKIP_LLM", false),
+		Fuzziness:            getEnvFuzziness("FUZZINESS", fuzzinessAuto),
+		SegmentBaseDict:      getEnv("SEGMENT_BASE_DICT", "./dict/tdict-std.txt"),
+		SegmentDictDir:       getEnv("SEGMENT_DICT_DIR", "./dict"),
+		ContentTokenizer:     getEnv("TOKENIZER_CONTENT", getEnv("TOKENIZER", "mapkha")),
+		HeadingTokenizer:     getEnv("TOKENIZER_HEADING", getEnv("TOKENIZER", "mapkha")),
+		QueryTokenizer:       getEnv("TOKENIZER_QUERY", getEnv("TOKENIZER", "mapkha")),
+		StopwordFile:         getEnv("STOPWORD_FILE", "./dict/stopwords.txt"),
+		MinKeywordLength:     getEnvInt("MIN_KEYWORD_LENGTH", 2),
+		KeywordMaxLineRatio:  getEnvFloat("KEYWORD_MAX_LINE_RATIO", 0.3),
+		KeywordMinLines:      getEnvInt("KEYWORD_MIN_LINES", 50),
 	}
 }
 
@@ -43,3 +160,72 @@ func getEnv(key, defaultValue string) string {
 	}
 	return defaultValue
 }
+
+// getEnvList อ่านค่าที่คั่นด้วย comma เช่น "gemini,deepseek"
+func getEnvList(key string, defaultValue []string) []string {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+
+	var list []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func getEnvInt(key string, defaultValue int) int {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("คำเตือน: %s=%q ไม่ใช่ตัวเลข ใช้ค่าเริ่มต้น %d", key, value, defaultValue)
+		return defaultValue
+	}
+	return n
+}
+
+func getEnvBool(key string, defaultValue bool) bool {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("คำเตือน: %s=%q ไม่ใช่ true/false ใช้ค่าเริ่มต้น %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return b
+}
+
+func getEnvFloat(key string, defaultValue float64) float64 {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	f, err := strconv.ParseFloat(value, 64)
+	if err != nil {
+		log.Printf("คำเตือน: %s=%q ไม่ใช่ตัวเลข ใช้ค่าเริ่มต้น %v", key, value, defaultValue)
+		return defaultValue
+	}
+	return f
+}
+
+// getEnvFuzziness อ่านค่า "auto" หรือ 0–2
+func getEnvFuzziness(key string, defaultValue fuzzinessLevel) fuzzinessLevel {
+	value := os.Getenv(key)
+	if value == "" {
+		return defaultValue
+	}
+	level, err := parseFuzziness(value)
+	if err != nil {
+		log.Printf("คำเตือน: %s=%q ไม่ถูกต้อง (%v) ใช้ค่าเริ่มต้น", key, value, err)
+		return defaultValue
+	}
+	return level
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"time"
+
+	_ "github.com/lib/pq"
+)
+
+var db *sql.DB
+
+// initDatabase เชื่อมต่อ PostgreSQL (pgvector) ตาม config
+func initDatabase(cfg *Config) error {
+	dsn := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		cfg.DBHost, cfg.DBPort, cfg.DBUser, cfg.DBPassword, cfg.DBName, cfg.DBSSLMode)
+
+	conn, err := sql.Open("postgres", dsn)
+	if err != nil {
+		return err
+	}
+
+	conn.SetMaxOpenConns(20)
+	conn.SetMaxIdleConns(5)
+	conn.SetConnMaxLifetime(30 * time.Minute)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := conn.PingContext(ctx); err != nil {
+		conn.Close()
+		return err
+	}
+
+	db = conn
+	log.Printf("✅ เชื่อมต่อ PostgreSQL สำเร็จ (%s:%s/%s)", cfg.DBHost, cfg.DBPort, cfg.DBName)
+	return nil
+}
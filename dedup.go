@@ -0,0 +1,129 @@
+package main
+
+import (
+	"fmt"
+	"hash/fnv"
+	"log"
+	"strings"
+	"unicode"
+)
+
+// dedupLimitFactor ถ้า request จำกัดจำนวนผลลัพธ์ ตรวจเนื้อหาซ้ำไม่เกินกี่เท่าของ limit
+// (ผลที่ซ้ำมักอยู่ใกล้กันในอันดับ จึงไม่ต้องตรวจผลที่ถูกตัดทิ้งอยู่แล้ว)
+const dedupLimitFactor = 4
+
+// shingleSize จำนวนตัวอักษรต่อ shingle (ภาษาไทยไม่มีช่องว่างระหว่างคำ จึงใช้ shingle แบบตัวอักษร)
+const shingleSize = 5
+
+// removeDuplicateMatches ลบผลลัพธ์ซ้ำของบรรทัดเดียวกัน (ไฟล์ + บรรทัด)
+func removeDuplicateMatches(matches []Match) []Match {
+	seen := make(map[string]bool)
+	var unique []Match
+
+	for _, match := range matches {
+		key := fmt.Sprintf("%s:%d", match.Filename, match.LineNum)
+		if !seen[key] {
+			seen[key] = true
+			unique = append(unique, match)
+		}
+	}
+
+	return unique
+}
+
+// collapseNearDuplicates รวมผลลัพธ์ที่เนื้อหาเหมือนหรือเกือบเหมือนกัน (รวมถึงต่างไฟล์กัน เช่น ย่อหน้าที่คัดลอกไปหลายเอกสาร)
+// matches ต้องเรียงตามคะแนนแล้ว ผลที่อยู่ก่อนจะถูกเก็บไว้ และนับจำนวนที่รวมเข้ามาใน Duplicates
+// เทียบทีละคู่ จึงตรวจเฉพาะ maxCandidates อันดับแรก (<= 0 = ทั้งหมด) ที่เหลือต่อท้ายโดยไม่ตรวจ
+// similarity <= 0 คือไม่รวม
+func collapseNearDuplicates(matches []Match, similarity float64, maxCandidates int) []Match {
+	if similarity <= 0 || len(matches) < 2 {
+		return matches
+	}
+	var rest []Match
+	if maxCandidates > 0 && len(matches) > maxCandidates {
+		matches, rest = matches[:maxCandidates], matches[maxCandidates:]
+	}
+
+	type fingerprint struct {
+		hash     uint64
+		shingles map[uint64]bool
+	}
+
+	var kept []Match
+	var prints []fingerprint
+	for _, match := range matches {
+		text := normalizeForDedup(strings.Join(match.Context, "\n"))
+		fp := fingerprint{hash: hashString(text), shingles: contentShingles(text)}
+
+		duplicateOf := -1
+		for i, other := range prints {
+			if fp.hash == other.hash || jaccard(fp.shingles, other.shingles) >= similarity {
+				duplicateOf = i
+				break
+			}
+		}
+		if duplicateOf >= 0 {
+			kept[duplicateOf].Duplicates += 1 + match.Duplicates
+			log.Printf("   ♻️  %s:%d ซ้ำกับ %s:%d", match.Filename, match.LineNum, kept[duplicateOf].Filename, kept[duplicateOf].LineNum)
+			continue
+		}
+
+		kept = append(kept, match)
+		prints = append(prints, fp)
+	}
+
+	if len(kept) < len(matches) {
+		log.Printf("♻️  รวมผลลัพธ์ที่เนื้อหาซ้ำ: %d → %d", len(matches), len(kept))
+	}
+	return append(kept, rest...)
+}
+
+// normalizeForDedup เก็บเฉพาะตัวอักษรและตัวเลขแบบตัวพิมพ์เล็ก เพื่อไม่ให้เครื่องหมาย markdown และช่องว่างมีผล
+func normalizeForDedup(text string) string {
+	var builder strings.Builder
+	for _, r := range normalizeText(text) {
+		if isThaiChar(r) || unicode.IsLetter(r) || unicode.IsNumber(r) {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// contentShingles hash ของทุกช่วง shingleSize ตัวอักษร (ข้อความสั้นกว่านั้นใช้ทั้งข้อความ)
+func contentShingles(text string) map[uint64]bool {
+	runes := []rune(text)
+	shingles := make(map[uint64]bool)
+	if len(runes) <= shingleSize {
+		if len(runes) > 0 {
+			shingles[hashString(text)] = true
+		}
+		return shingles
+	}
+	for i := 0; i+shingleSize <= len(runes); i++ {
+		shingles[hashString(string(runes[i:i+shingleSize]))] = true
+	}
+	return shingles
+}
+
+// jaccard ความคล้ายของสองเซต = |A ∩ B| / |A ∪ B|
+func jaccard(a, b map[uint64]bool) float64 {
+	if len(a) == 0 || len(b) == 0 {
+		return 0
+	}
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	common := 0
+	for key := range a {
+		if b[key] {
+			common++
+		}
+	}
+	return float64(common) / float64(len(a)+len(b)-common)
+}
+
+func hashString(text string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(text))
+	return h.Sum64()
+}
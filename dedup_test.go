@@ -0,0 +1,87 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCollapseNearDuplicates(t *testing.T) {
+	match := func(file string, text ...string) Match {
+		return Match{Filename: file, LineNum: 1, Context: text}
+	}
+	policy := "พนักงานที่ทำงานครบ 1 ปี มีสิทธิลาพักร้อนได้ 6 วันทำงาน โดยได้รับค่าจ้างตามปกติ"
+
+	type result struct {
+		file       string
+		duplicates int
+	}
+	tests := []struct {
+		name          string
+		matches       []Match
+		maxCandidates int
+		want          []result
+	}{
+		{
+			name:    "เนื้อหาเดียวกันต่างไฟล์",
+			matches: []Match{match("a.md", policy), match("b.md", policy)},
+			want:    []result{{"a.md", 1}},
+		},
+		{
+			name:    "ต่างกันแค่เครื่องหมาย markdown และช่องว่าง",
+			matches: []Match{match("a.md", "- "+policy), match("b.md", "**"+policy+"**  ")},
+			want:    []result{{"a.md", 1}},
+		},
+		{
+			name: "เนื้อหาต่างกัน",
+			matches: []Match{
+				match("a.md", policy),
+				match("b.md", "ปูนซีเมนต์ตราช้าง ลด 15% ซื้อครบ 50 ถุง"),
+			},
+			want: []result{{"a.md", 0}, {"b.md", 0}},
+		},
+		{
+			name: "นับรวม Duplicates เดิม",
+			matches: []Match{
+				match("a.md", policy),
+				{Filename: "b.md", Context: []string{policy}, Duplicates: 2},
+				match("c.md", policy),
+			},
+			want: []result{{"a.md", 4}},
+		},
+		{
+			name: "ตรวจเฉพาะอันดับต้นๆ",
+			matches: []Match{
+				match("a.md", policy),
+				match("b.md", "ปูนซีเมนต์ตราช้าง ลด 15% ซื้อครบ 50 ถุง"),
+				match("c.md", policy),
+			},
+			maxCandidates: 2,
+			want:          []result{{"a.md", 0}, {"b.md", 0}, {"c.md", 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []result
+			for _, m := range collapseNearDuplicates(tt.matches, 0.9, tt.maxCandidates) {
+				got = append(got, result{m.Filename, m.Duplicates})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("collapseNearDuplicates = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJaccard(t *testing.T) {
+	a := contentShingles("abcdefgh")
+	if got := jaccard(a, a); got != 1 {
+		t.Errorf("jaccard(a, a) = %v, want 1", got)
+	}
+	if got := jaccard(a, contentShingles("zyxwvuts")); got != 0 {
+		t.Errorf("jaccard ของข้อความที่ไม่มีส่วนเหมือน = %v, want 0", got)
+	}
+	if got := jaccard(a, map[uint64]bool{}); got != 0 {
+		t.Errorf("jaccard กับเซตว่าง = %v, want 0", got)
+	}
+}
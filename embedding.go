@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// OllamaEmbeddingRequest for Ollama embeddings API
+type OllamaEmbeddingRequest struct {
+	Model  string `json:"model"`
+	Prompt string `json:"prompt"`
+}
+
+// OllamaEmbeddingResponse from Ollama
+type OllamaEmbeddingResponse struct {
+	Embedding []float64 `json:"embedding"`
+}
+
+// createEmbedding สร้าง embedding ของข้อความด้วย Ollama (model ตาม Config.OllamaModel)
+func createEmbedding(ctx context.Context, cfg *Config, text string) ([]float64, error) {
+	jsonData, err := json.Marshal(OllamaEmbeddingRequest{
+		Model:  cfg.OllamaModel,
+		Prompt: text,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST", cfg.OllamaHost+"/api/embeddings", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := apiHTTPClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return nil, fmt.Errorf("ollama embeddings error: %s", string(body))
+	}
+
+	var embResp OllamaEmbeddingResponse
+	if err := json.NewDecoder(resp.Body).Decode(&embResp); err != nil {
+		return nil, err
+	}
+	if len(embResp.Embedding) == 0 {
+		return nil, fmt.Errorf("ollama คืน embedding ว่าง")
+	}
+
+	return embResp.Embedding, nil
+}
+
+// vectorLiteral แปลง embedding เป็นรูปแบบข้อความของ pgvector เช่น [0.1,0.2]
+func vectorLiteral(embedding []float64) string {
+	var builder strings.Builder
+	builder.WriteByte('[')
+	for i, v := range embedding {
+		if i > 0 {
+			builder.WriteByte(',')
+		}
+		builder.WriteString(strconv.FormatFloat(v, 'f', -1, 32))
+	}
+	builder.WriteByte(']')
+	return builder.String()
+}
@@ -0,0 +1,299 @@
+package main
+
+import (
+	"container/list"
+	"encoding/json"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// expansionCacheEntry ผลการขยายคำค้นหา 1 รายการ
+type expansionCacheEntry struct {
+	Key       string          `json:"key"`
+	Query     string          `json:"query"`
+	Model     string          `json:"model"`
+	Expansion expansionResult `json:"expansion"`
+	CreatedAt time.Time       `json:"created_at"`
+	ExpiresAt time.Time       `json:"expires_at"`
+	Hits      int             `json:"hits"`
+}
+
+// expansionCacheVersion เปลี่ยนเมื่อรูปแบบ expansionCacheEntry เปลี่ยน ไฟล์รุ่นเก่าจะถูกทิ้ง
+// (รุ่นแรกเก็บเป็น array ของ {"keywords": [...]} ซึ่งโหลดมาเป็น expansion ว่าง)
+const expansionCacheVersion = 2
+
+// expansionCacheFile รูปแบบไฟล์ cache บน disk
+type expansionCacheFile struct {
+	Version int                   `json:"version"`
+	Entries []expansionCacheEntry `json:"entries"`
+}
+
+// expansionCacheStats สถิติของ cache (สำหรับ admin endpoint)
+type expansionCacheStats struct {
+	Entries  int     `json:"entries"`
+	Capacity int     `json:"capacity"`
+	TTL      string  `json:"ttl"`
+	Hits     uint64  `json:"hits"`
+	Misses   uint64  `json:"misses"`
+	HitRate  float64 `json:"hit_rate"`
+	File     string  `json:"file,omitempty"`
+}
+
+// queryExpansionCache LRU cache ของผลการขยายคำค้นหา มี TTL และบันทึกลง disk ได้
+type queryExpansionCache struct {
+	mu       sync.Mutex
+	capacity int // 0 = ปิด cache
+	ttl      time.Duration
+	file     string // ว่าง = ไม่บันทึกลง disk
+	order    *list.List
+	items    map[string]*list.Element
+	hits     uint64
+	misses   uint64
+	dirty    bool
+}
+
+var expansionCache *queryExpansionCache
+
+func newQueryExpansionCache(capacity int, ttl time.Duration, file string) *queryExpansionCache {
+	return &queryExpansionCache{
+		capacity: capacity,
+		ttl:      ttl,
+		file:     file,
+		order:    list.New(),
+		items:    make(map[string]*list.Element),
+	}
+}
+
+// expansionCacheKey key จากคำค้นหาที่ normalize แล้ว (ตัวพิมพ์เล็ก, ช่องว่างเดียว) และชื่อ model
+func expansionCacheKey(query, model string) string {
+	return model + "|" + normalizeCacheQuery(query)
+}
+
+func normalizeCacheQuery(query string) string {
+	return strings.TrimSpace(normalizeText(query))
+}
+
+// get คืนผลการขยายคำค้นหา ถ้ามีใน cache และยังไม่หมดอายุ
+func (c *queryExpansionCache) get(query, model string) (expansionResult, bool) {
+	if c == nil || c.capacity <= 0 {
+		return expansionResult{}, false
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, exists := c.items[expansionCacheKey(query, model)]
+	if !exists {
+		c.misses++
+		return expansionResult{}, false
+	}
+
+	entry := el.Value.(*expansionCacheEntry)
+	if c.ttl > 0 && time.Now().After(entry.ExpiresAt) {
+		c.removeLocked(el)
+		c.misses++
+		return expansionResult{}, false
+	}
+
+	entry.Hits++
+	c.hits++
+	c.order.MoveToFront(el)
+	return entry.Expansion, true
+}
+
+// put เก็บผลการขยายคำค้นหา (ถ้าเต็มจะลบรายการที่ใช้ล่าสุดนานที่สุด)
+func (c *queryExpansionCache) put(query, model string, expansion expansionResult) {
+	if c == nil || c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := expansionCacheKey(query, model)
+	if el, exists := c.items[key]; exists {
+		c.removeLocked(el)
+	}
+
+	now := time.Now()
+	entry := &expansionCacheEntry{
+		Key:       key,
+		Query:     query,
+		Model:     model,
+		Expansion: expansion,
+		CreatedAt: now,
+		ExpiresAt: now.Add(c.ttl),
+	}
+	c.items[key] = c.order.PushFront(entry)
+	c.dirty = true
+
+	for c.order.Len() > c.capacity {
+		c.removeLocked(c.order.Back())
+	}
+}
+
+func (c *queryExpansionCache) removeLocked(el *list.Element) {
+	entry := c.order.Remove(el).(*expansionCacheEntry)
+	delete(c.items, entry.Key)
+	c.dirty = true
+}
+
+// purge ลบรายการของคำค้นหา (ทุก model) หรือทั้งหมดเมื่อ query ว่าง คืนจำนวนที่ลบ
+func (c *queryExpansionCache) purge(query string) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	normalized := normalizeCacheQuery(query)
+	removed := 0
+	for el := c.order.Front(); el != nil; {
+		next := el.Next()
+		entry := el.Value.(*expansionCacheEntry)
+		if query == "" || normalizeCacheQuery(entry.Query) == normalized {
+			c.removeLocked(el)
+			removed++
+		}
+		el = next
+	}
+	return removed
+}
+
+// snapshot คืนสถิติและรายการทั้งหมด (ใช้ล่าสุดก่อน)
+func (c *queryExpansionCache) snapshot() (expansionCacheStats, []expansionCacheEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stats := expansionCacheStats{
+		Entries:  c.order.Len(),
+		Capacity: c.capacity,
+		TTL:      c.ttl.String(),
+		Hits:     c.hits,
+		Misses:   c.misses,
+		File:     c.file,
+	}
+	if total := c.hits + c.misses; total > 0 {
+		stats.HitRate = float64(c.hits) / float64(total)
+	}
+
+	entries := make([]expansionCacheEntry, 0, c.order.Len())
+	for el := c.order.Front(); el != nil; el = el.Next() {
+		entries = append(entries, *el.Value.(*expansionCacheEntry))
+	}
+	return stats, entries
+}
+
+// load โหลด cache จากไฟล์ JSON (ข้ามรายการที่หมดอายุแล้ว)
+func (c *queryExpansionCache) load() error {
+	if c.file == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(c.file)
+	if err != nil {
+		return err
+	}
+	var file expansionCacheFile
+	if err := json.Unmarshal(data, &file); err != nil || file.Version != expansionCacheVersion {
+		log.Printf("⚠️  expansion cache %s เป็นรูปแบบเก่า → ทิ้งแล้วเริ่มใหม่", c.file)
+		return os.Remove(c.file)
+	}
+	entries := file.Entries
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	now := time.Now()
+	// ไฟล์เก็บแบบใช้ล่าสุดก่อน → ใส่จากท้ายเพื่อให้ลำดับ LRU เหมือนเดิม
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		if c.ttl > 0 && now.After(entry.ExpiresAt) {
+			continue
+		}
+		if el, exists := c.items[entry.Key]; exists {
+			c.order.Remove(el)
+		}
+		c.items[entry.Key] = c.order.PushFront(&entry)
+	}
+	for c.order.Len() > c.capacity {
+		c.removeLocked(c.order.Back())
+	}
+	c.dirty = false
+	return nil
+}
+
+// save บันทึก cache ลงไฟล์ (เฉพาะเมื่อมีการเปลี่ยนแปลง)
+func (c *queryExpansionCache) save() error {
+	if c.file == "" {
+		return nil
+	}
+
+	c.mu.Lock()
+	if !c.dirty {
+		c.mu.Unlock()
+		return nil
+	}
+	entries := make([]expansionCacheEntry, 0, c.order.Len())
+	for el := c.order.Front(); el != nil; el = el.Next() {
+		entries = append(entries, *el.Value.(*expansionCacheEntry))
+	}
+	c.dirty = false
+	c.mu.Unlock()
+
+	err := writeJSONFile(c.file, expansionCacheFile{Version: expansionCacheVersion, Entries: entries})
+	if err != nil {
+		// บันทึกไม่สำเร็จ → ลองใหม่รอบหน้า
+		c.mu.Lock()
+		c.dirty = true
+		c.mu.Unlock()
+	}
+	return err
+}
+
+// writeJSONFile เขียน JSON ลงไฟล์ชั่วคราวแล้ว rename
+func writeJSONFile(path string, v interface{}) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	return replaceFile(path, data)
+}
+
+// replaceFile เขียนไฟล์ชั่วคราวชื่อไม่ซ้ำในโฟลเดอร์เดียวกันแล้ว rename ทับ (ผู้อ่านไม่เห็นไฟล์ที่เขียนไม่ครบ)
+func replaceFile(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // rename สำเร็จแล้วจะไม่มีไฟล์นี้
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Chmod(0644); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// persist บันทึก cache ลง disk เป็นระยะ
+func (c *queryExpansionCache) persist(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if err := c.save(); err != nil {
+			log.Printf("⚠️  บันทึก expansion cache ไม่สำเร็จ: %v", err)
+		}
+	}
+}
@@ -0,0 +1,362 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// การค้นหาคำที่สะกดผิดด้วย edit distance (Damerau-Levenshtein) เทียบกับคำทั้งหมดใน index
+// นับระยะเป็น grapheme cluster ไม่ใช่ byte: "บื้" (พยัญชนะ + สระบน + วรรณยุกต์) คือ 1 ตัว
+// การลืมใส่วรรณยุกต์ ("กระเบือง" → "กระเบื้อง") จึงห่าง 1 เหมือนพิมพ์ผิด 1 ตัวในภาษาอังกฤษ
+// ใช้วิธีแบบ SymSpell: เก็บคำที่ลบตัวอักษรออก 0–maxFuzzyEdits ตัวของทุกคำไว้ล่วงหน้า
+// ตอนค้นหาจึงเทียบเฉพาะคำที่มีรูปลบร่วมกับคำค้นหา ไม่ต้องเทียบทุกคำ
+
+// fuzzinessAuto จำนวนตัวที่ผิดได้ขึ้นกับความยาวคำ (ดู autoFuzzyEdits)
+const fuzzinessAuto = -1
+
+// maxFuzzyEdits จำนวนตัวที่ผิดได้สูงสุด (มากกว่านี้รูปลบจะมีมากเกินไปและได้คำที่ไม่เกี่ยวข้อง)
+const maxFuzzyEdits = 2
+
+// minFuzzyClusters คำที่สั้นกว่านี้ไม่ค้นหาแบบ fuzzy (คำสั้นผิด 1 ตัวก็กลายเป็นคำอื่นแล้ว)
+const minFuzzyClusters = 3
+
+// maxFuzzyClusters คำที่ยาวกว่านี้ไม่เก็บรูปลบ (จำนวนรูปลบโตตามความยาวกำลังสอง)
+const maxFuzzyClusters = 24
+
+// maxFuzzyCandidates จำนวนคำที่ใกล้เคียงสูงสุดต่อคำค้นหา 1 คำ
+const maxFuzzyCandidates = 3
+
+// fuzzinessLevel ค่า fuzziness ใน request/Config: "auto" หรือจำนวนตัวที่ผิดได้ (0 = ปิด)
+type fuzzinessLevel int
+
+func (f *fuzzinessLevel) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		text = string(data) // ตัวเลข
+	}
+	level, err := parseFuzziness(text)
+	if err != nil {
+		return err
+	}
+	*f = level
+	return nil
+}
+
+// parseFuzziness แปลง "auto", "0", "1", "2"
+func parseFuzziness(text string) (fuzzinessLevel, error) {
+	text = strings.TrimSpace(text)
+	if strings.EqualFold(text, "auto") {
+		return fuzzinessAuto, nil
+	}
+	n, err := strconv.Atoi(text)
+	if err != nil || n < 0 || n > maxFuzzyEdits {
+		return 0, fmt.Errorf("fuzziness ไม่ถูกต้อง: %s (auto หรือ 0–%d)", text, maxFuzzyEdits)
+	}
+	return fuzzinessLevel(n), nil
+}
+
+// maxEdits จำนวนตัวที่ผิดได้ของคำที่ยาว clusters ตัว
+func (f fuzzinessLevel) maxEdits(clusters int) int {
+	if clusters < minFuzzyClusters {
+		return 0
+	}
+	if f == fuzzinessAuto {
+		return autoFuzzyEdits(clusters)
+	}
+	return min(int(f), maxFuzzyEdits)
+}
+
+// autoFuzzyEdits 3–5 ตัวผิดได้ 1, 6 ตัวขึ้นไปผิดได้ 2
+func autoFuzzyEdits(clusters int) int {
+	switch {
+	case clusters < minFuzzyClusters:
+		return 0
+	case clusters <= 5:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// graphemeClusters แบ่งคำเป็นตัวอักษรที่มองเห็น: สระบน/ล่างและวรรณยุกต์ (Mn) ติดกับตัวก่อนหน้า
+func graphemeClusters(word string) []string {
+	var clusters []string
+	for i, r := range word {
+		if unicode.Is(unicode.Mn, r) && len(clusters) > 0 {
+			clusters[len(clusters)-1] += string(r)
+			continue
+		}
+		clusters = append(clusters, word[i:i+len(string(r))])
+	}
+	return clusters
+}
+
+// damerauLevenshtein ระยะ edit (แทน/ลบ/เพิ่ม/สลับตัวติดกัน) แบบ optimal string alignment
+// คืน limit+1 ทันทีเมื่อรู้ว่าเกิน limit
+func damerauLevenshtein(a, b []string, limit int) int {
+	if diff := len(a) - len(b); diff > limit || -diff > limit {
+		return limit + 1
+	}
+
+	prev2 := make([]int, len(b)+1)
+	prev := make([]int, len(b)+1)
+	curr := make([]int, len(b)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+
+	for i := 1; i <= len(a); i++ {
+		curr[0] = i
+		rowMin := curr[0]
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			curr[j] = min(min(prev[j]+1, curr[j-1]+1), prev[j-1]+cost)
+			if i > 1 && j > 1 && a[i-1] == b[j-2] && a[i-2] == b[j-1] {
+				curr[j] = min(curr[j], prev2[j-2]+1)
+			}
+			rowMin = min(rowMin, curr[j])
+		}
+		if rowMin > limit {
+			return limit + 1
+		}
+		prev2, prev, curr = prev, curr, prev2
+	}
+	return prev[len(b)]
+}
+
+// fuzzyDeletes รูปที่ลบ cluster ออก 0–edits ตัว (รวมคำเดิม)
+func fuzzyDeletes(clusters []string, edits int) map[string]bool {
+	variants := map[string]bool{strings.Join(clusters, ""): true}
+	frontier := [][]string{clusters}
+	for e := 0; e < edits; e++ {
+		var next [][]string
+		for _, word := range frontier {
+			for i := range word {
+				deleted := make([]string, 0, len(word)-1)
+				deleted = append(deleted, word[:i]...)
+				deleted = append(deleted, word[i+1:]...)
+				key := strings.Join(deleted, "")
+				if !variants[key] {
+					variants[key] = true
+					next = append(next, deleted)
+				}
+			}
+		}
+		frontier = next
+	}
+	return variants
+}
+
+// fuzzyVocabulary รูปลบ → คำใน index ที่ลบแล้วได้รูปนั้น (สร้างใหม่ตอนโหลด ไม่บันทึกลง disk)
+type fuzzyVocabulary struct {
+	deletes map[string][]string
+}
+
+func newFuzzyVocabulary() *fuzzyVocabulary {
+	return &fuzzyVocabulary{deletes: make(map[string][]string)}
+}
+
+// fuzzyIndexable คำที่ควรค้นหาแบบ fuzzy ได้ (ไม่รวมตัวเลข เพราะ "100" กับ "10" คนละความหมาย)
+func fuzzyIndexable(clusters []string) bool {
+	if len(clusters) < minFuzzyClusters || len(clusters) > maxFuzzyClusters {
+		return false
+	}
+	for _, c := range clusters {
+		if strings.IndexFunc(c, unicode.IsDigit) >= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+func (v *fuzzyVocabulary) add(term string) {
+	clusters := graphemeClusters(term)
+	if !fuzzyIndexable(clusters) {
+		return
+	}
+	for variant := range fuzzyDeletes(clusters, maxFuzzyEdits) {
+		v.deletes[variant] = append(v.deletes[variant], term)
+	}
+}
+
+func (v *fuzzyVocabulary) remove(term string) {
+	clusters := graphemeClusters(term)
+	if !fuzzyIndexable(clusters) {
+		return
+	}
+	for variant := range fuzzyDeletes(clusters, maxFuzzyEdits) {
+		list := v.deletes[variant]
+		kept := list[:0]
+		for _, t := range list {
+			if t != term {
+				kept = append(kept, t)
+			}
+		}
+		if len(kept) == 0 {
+			delete(v.deletes, variant)
+		} else {
+			v.deletes[variant] = kept
+		}
+	}
+}
+
+// lookup คำใน vocabulary ที่ห่างจาก word ไม่เกิน edits พร้อมระยะ
+func (v *fuzzyVocabulary) lookup(word string, edits int) map[string]int {
+	clusters := graphemeClusters(word)
+	found := make(map[string]int)
+	for variant := range fuzzyDeletes(clusters, edits) {
+		for _, term := range v.deletes[variant] {
+			if _, checked := found[term]; checked {
+				continue
+			}
+			found[term] = damerauLevenshtein(clusters, graphemeClusters(term), edits)
+		}
+	}
+	for term, distance := range found {
+		if distance > edits || term == word {
+			delete(found, term)
+		}
+	}
+	return found
+}
+
+// fuzzyTerms หาคำในเอกสารของ shop ที่ใกล้เคียงกับคำในคำค้นหาที่ไม่มีใน index (น่าจะสะกดผิด)
+// เก็บเฉพาะคำที่ห่างน้อยที่สุดของแต่ละคำค้นหา ("กระเบือง" → "กระเบื้อง" ไม่เอา "กระป๋อง" ที่ห่าง 2)
+func (idx *InvertedIndex) fuzzyTerms(shopID, query string, fuzziness fuzzinessLevel) []string {
+	if fuzziness == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var result []string
+	var matched []fuzzySpan
+	for _, span := range fuzzyQuerySpans(shopID, query) {
+		if span.within(matched) || idx.termCountLocked(shopID, span.Text) > 0 {
+			continue
+		}
+		edits := fuzziness.maxEdits(len(graphemeClusters(span.Text)))
+		if edits == 0 {
+			continue
+		}
+
+		type candidate struct {
+			term     string
+			distance int
+			count    int
+		}
+		var candidates []candidate
+		for term, distance := range idx.fuzzy.lookup(span.Text, edits) {
+			if strings.Contains(span.Text, term) {
+				continue // เป็นส่วนหนึ่งของคำค้นหาอยู่แล้ว (เช่น "เมน" ใน "ซีเมน") ไม่ใช่คำที่แก้การสะกด
+			}
+			if count := idx.termCountLocked(shopID, term); count > 0 {
+				candidates = append(candidates, candidate{term, distance, count})
+			}
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			if candidates[i].distance != candidates[j].distance {
+				return candidates[i].distance < candidates[j].distance
+			}
+			if candidates[i].count != candidates[j].count {
+				return candidates[i].count > candidates[j].count
+			}
+			return candidates[i].term < candidates[j].term
+		})
+		for i := 0; i < len(candidates) && i < maxFuzzyCandidates; i++ {
+			if candidates[i].distance > candidates[0].distance {
+				break
+			}
+			result = append(result, candidates[i].term)
+		}
+		matched = append(matched, span)
+	}
+	return result
+}
+
+// fuzzySpan ส่วนของคำค้นหา (byte offset) ที่ใช้เทียบ
+type fuzzySpan struct {
+	Text       string
+	Start, End int
+}
+
+// within span นี้อยู่ในส่วนที่หาคำใกล้เคียงได้แล้วหรือไม่
+func (s fuzzySpan) within(spans []fuzzySpan) bool {
+	for _, other := range spans {
+		if s.Start >= other.Start && s.End <= other.End {
+			return true
+		}
+	}
+	return false
+}
+
+// fuzzyQuerySpans ส่วนของคำค้นหาที่ใช้เทียบ เรียงจากยาวไปสั้น:
+// คำภาษาไทยที่เขียนติดกันทั้งคำ, token ภาษาไทยที่อยู่ติดกัน 2–maxTranslitNgram ตัว และคำภาษาอังกฤษทีละคำ
+// ไม่เทียบ token ไทยตัวเดียว เพราะคำที่สะกดผิดจะถูกตัดเป็นเศษคำ (เช่น "กระ|เบือ|ง") ที่ใกล้กับคำอื่นโดยบังเอิญ
+func fuzzyQuerySpans(shopID, query string) []fuzzySpan {
+	lower := normalizeText(query)
+	seen := make(map[[2]int]bool)
+	var spans []fuzzySpan
+	add := func(start, end int) {
+		if !seen[[2]int{start, end}] {
+			seen[[2]int{start, end}] = true
+			spans = append(spans, fuzzySpan{Text: lower[start:end], Start: start, End: end})
+		}
+	}
+
+	tokens := tokenizeLine(queryTokenizer, shopID, lower)
+	for i, tok := range tokens {
+		if !hasThaiCharacters(tok.Term) {
+			add(tok.Pos, tok.Pos+len(tok.Term))
+			continue
+		}
+
+		end := tok.Pos + len(tok.Term)
+		for n := 1; n < maxTranslitNgram && i+n < len(tokens); n++ {
+			next := tokens[i+n]
+			if next.Pos != end || !hasThaiCharacters(next.Term) {
+				break
+			}
+			end = next.Pos + len(next.Term)
+			add(tok.Pos, end)
+		}
+	}
+
+	start := -1
+	for i, r := range lower + " " {
+		switch {
+		case isThaiChar(r) && start < 0:
+			start = i
+		case !isThaiChar(r) && start >= 0:
+			add(start, i)
+			start = -1
+		}
+	}
+
+	sort.SliceStable(spans, func(i, j int) bool {
+		return len(spans[i].Text) > len(spans[j].Text)
+	})
+	return spans
+}
+
+// termCountLocked จำนวนครั้งที่ term อยู่ในเอกสารของ shopID
+func (idx *InvertedIndex) termCountLocked(shopID, term string) int {
+	count := 0
+	for _, p := range idx.postings[term] {
+		if idx.docs[p.Doc].ShopID == shopID {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestGraphemeClusters(t *testing.T) {
+	tests := []struct {
+		word string
+		want []string
+	}{
+		{"", nil},
+		{"pvc", []string{"p", "v", "c"}},
+		{"ที่นั่ง", []string{"ที่", "นั่", "ง"}},
+		{"กระเบื้อง", []string{"ก", "ร", "ะ", "เ", "บื้", "อ", "ง"}},
+		{"น้ำ", []string{"น้", "ำ"}},
+		{"่ก", []string{"่", "ก"}}, // เครื่องหมายตัวแรกไม่มีตัวให้ติด
+	}
+
+	for _, tt := range tests {
+		if got := graphemeClusters(tt.word); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("graphemeClusters(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestDamerauLevenshtein(t *testing.T) {
+	tests := []struct {
+		name  string
+		a, b  string
+		limit int
+		want  int
+	}{
+		{"เหมือนกัน", "กระเบื้อง", "กระเบื้อง", 2, 0},
+		{"ว่างทั้งคู่", "", "", 2, 0},
+		{"แทน 1 ตัว", "ปูน", "ปูม", 2, 1},
+		{"ลบ 1 ตัว", "pvc", "pc", 2, 1},
+		{"เพิ่ม 1 ตัว", "สี", "สีน", 2, 1},
+		{"สลับตัวติดกัน", "jotun", "jotnu", 2, 1},
+		{"สลับตัวติดกันภาษาไทย", "ทราย", "ทรยา", 2, 1},
+		{"วรรณยุกต์หายนับเป็น 1", "กระเบื้อง", "กระเบือง", 2, 1},
+		{"แทน 2 ตัว", "cement", "semant", 2, 2},
+		{"ความยาวต่างเกิน limit", "ปูน", "ปูนซีเมนต์", 2, 3},
+		{"ต่างกันทุกตัวเกิน limit", "abcd", "wxyz", 1, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := damerauLevenshtein(graphemeClusters(tt.a), graphemeClusters(tt.b), tt.limit)
+			if tt.want > tt.limit {
+				if got <= tt.limit {
+					t.Errorf("damerauLevenshtein(%q, %q, %d) = %d, want > %d", tt.a, tt.b, tt.limit, got, tt.limit)
+				}
+				return
+			}
+			if got != tt.want {
+				t.Errorf("damerauLevenshtein(%q, %q, %d) = %d, want %d", tt.a, tt.b, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
@@ -5,3 +5,7 @@ go 1.21
 require github.com/joho/godotenv v1.5.1
 
 require github.com/veer66/mapkha v0.0.0-20180827014328-4c22c721f2c6
+
+require github.com/lib/pq v1.12.3
+
+require golang.org/x/text v0.22.0
@@ -1,24 +1,43 @@
 package main
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"path/filepath"
 	"strings"
-	"sync"
+	"time"
+)
+
+// โหมดการค้นหา
+const (
+	searchModeText   = "text"
+	searchModeVector = "vector"
+	searchModeHybrid = "hybrid"
 )
 
 // SearchRequest for text search
 type SearchRequestSimple struct {
-	Query      string `json:"query"`
-	UseSummary bool   `json:"useSummary"`
+	Query            string          `json:"query"`
+	UseSummary       bool            `json:"useSummary"`
+	Mode             string          `json:"mode,omitempty"`            // text (ค่าเริ่มต้น), vector, hybrid
+	ShopID           string          `json:"shopid,omitempty"`          // ต้องระบุ (ยกเว้นเปิด AllowDefaultShopID)
+	Limit            int             `json:"limit,omitempty"`           // จำนวนผลลัพธ์สูงสุด
+	Threshold        *float64        `json:"threshold,omitempty"`       // similarity ขั้นต่ำ (vector)
+	HighlightPreTag  string          `json:"highlightPreTag,omitempty"` // ไม่ระบุ = Config.HighlightPreTag
+	HighlightPostTag string          `json:"highlightPostTag,omitempty"`
+	Expand           *bool           `json:"expand,omitempty"`      // ขยายคำค้นหาด้วย LLM (ไม่ระบุ = Config.ExpansionEnabled)
+	MaxKeywords      int             `json:"maxKeywords,omitempty"` // จำนวนคำค้นหาสูงสุดหลังขยาย
+	Fuzziness        *fuzzinessLevel `json:"fuzziness,omitempty"`   // "auto" หรือจำนวนตัวที่สะกดผิดได้ 0–2 (ไม่ระบุ = Config.Fuzziness)
 }
 
 // SearchResponse for text search
 type SearchResponseSimple struct {
 	Query   string               `json:"query"`
+	Mode    string               `json:"mode,omitempty"`
+	ShopID  string               `json:"shopid,omitempty"`
 	Results []SearchResultSimple `json:"results"`
 	Total   int                  `json:"total"`
 	Summary string               `json:"summary,omitempty"`
@@ -26,15 +45,36 @@ type SearchResponseSimple struct {
 }
 
 type SearchResultSimple struct {
-	Content  string `json:"content"`
-	Filename string `json:"filename"`
-	LineNum  int    `json:"line_number"`
+	Content     string      `json:"content"`
+	Highlighted string      `json:"highlighted"` // content ที่ escape เป็น HTML แล้วครอบคำค้นหาด้วย tag
+	Filename    string      `json:"filename"`
+	LineNum     int         `json:"line_number"`
+	HitLines    []int       `json:"hit_lines,omitempty"`    // ทุกบรรทัดที่เจอคำค้นหาใน passage
+	Path        string      `json:"path"`                   // path relative กับโฟลเดอร์ของ shop (ไม่ชนกันเมื่อชื่อไฟล์ซ้ำในโฟลเดอร์ย่อย)
+	HeadingPath string      `json:"heading_path,omitempty"` // เช่น "บทที่ 2 > 2.1 คุณสมบัติของผู้สมัครงาน"
+	Headings    []string    `json:"headings,omitempty"`
+	StartLine   int         `json:"start_line"`
+	EndLine     int         `json:"end_line"`
+	Keywords    []string    `json:"keywords,omitempty"`
+	Hits        []SearchHit `json:"hits,omitempty"`
+	Score       float64     `json:"score"`
+	Chunk       int         `json:"chunk,omitempty"`      // ลำดับ chunk (เริ่มที่ 1) สำหรับผลจาก vector
+	Similarity  float64     `json:"similarity,omitempty"` // cosine similarity สำหรับผลจาก vector
+	Duplicates  int         `json:"duplicates,omitempty"` // จำนวนผลลัพธ์ที่เนื้อหาซ้ำถูกรวมเข้ามา
+}
+
+// SearchHit ตำแหน่งคำค้นหาที่เจอ: Start/End เป็น byte offset ใน Content
+type SearchHit struct {
+	Keyword string `json:"keyword"`
+	LineNum int    `json:"line_number"`
+	Start   int    `json:"start"`
+	End     int    `json:"end"`
 }
 
 func enableCORSSimple(w http.ResponseWriter) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
-	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
-	w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+	w.Header().Set("Access-Control-Allow-Methods", "GET, POST, DELETE, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Content-Type, Authorization, X-Admin-Token")
 }
 
 func healthHandlerSimple(w http.ResponseWriter, r *http.Request) {
@@ -64,103 +104,202 @@ func searchHandlerSimple(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// client ที่ขอ text/event-stream จะได้ผลแบบ streaming
+	if strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		streamSearchHandler(w, r)
+		return
+	}
+
+	req, status, err := decodeSearchRequest(r)
+	if err != nil {
+		writeSearchResponse(w, status, SearchResponseSimple{Error: err.Error()})
+		return
+	}
+
+	uniqueMatches, err := executeSearch(r.Context(), req)
+	if err != nil {
+		writeSearchResponse(w, http.StatusInternalServerError, SearchResponseSimple{Query: req.Query, Mode: req.Mode, ShopID: req.ShopID, Error: err.Error()})
+		return
+	}
+
+	// สร้างสรุปด้วย AI ถ้าต้องการ
+	var summary string
+	if req.UseSummary && len(uniqueMatches) > 0 {
+		log.Printf("🤖 กำลังสรุปผลด้วย AI...")
+		contextForAI := formatMatchesForAI(uniqueMatches, req.Query)
+		// ✨ เพิ่ม filename + line_number ให้ AI ได้ข้อมูลแหล่งที่มา
+		sourceInfo := buildSourceInfo(uniqueMatches)
+		summary = summarizeResultsSimple(r.Context(), contextForAI, req.Query, sourceInfo)
+		if summary != "" {
+			log.Printf("✅ สรุปด้วย AI สำเร็จ")
+		}
+	}
+
+	results := toSearchResults(uniqueMatches, req)
+	writeSearchResponse(w, http.StatusOK, SearchResponseSimple{
+		Query:   req.Query,
+		Mode:    req.Mode,
+		ShopID:  req.ShopID,
+		Results: results,
+		Total:   len(results),
+		Summary: summary,
+	})
+}
+
+func writeSearchResponse(w http.ResponseWriter, status int, response SearchResponseSimple) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// decodeSearchRequest อ่านและตรวจสอบ request (คืน status code เมื่อไม่ถูกต้อง)
+func decodeSearchRequest(r *http.Request) (SearchRequestSimple, int, error) {
 	var req SearchRequestSimple
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		response := SearchResponseSimple{Error: "รูปแบบ JSON ไม่ถูกต้อง"}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
-		return
+		return req, http.StatusBadRequest, fmt.Errorf("รูปแบบ JSON ไม่ถูกต้อง")
 	}
 
+	// filter (file:, heading:) และ -คำ ใช้กรองผลลัพธ์ของคำค้นหาเท่านั้น ส่งมาอย่างเดียวไม่ได้
+	// (ไม่มีคำให้ค้นใน index และการคืนทุกบรรทัดของไฟล์ไม่ใช่ผลการค้นหา)
 	if req.Query == "" {
-		response := SearchResponseSimple{Error: "ต้องระบุคำค้นหา"}
-		w.Header().Set("Content-Type", "application/json")
-		w.WriteHeader(http.StatusBadRequest)
-		json.NewEncoder(w).Encode(response)
-		return
+		return req, http.StatusBadRequest, fmt.Errorf("ต้องระบุคำค้นหา")
+	}
+	if parseQuery(req.Query).isEmpty() {
+		return req, http.StatusBadRequest, fmt.Errorf("ต้องระบุคำค้นหาอย่างน้อย 1 คำ (file:, heading: และ -คำ ใช้กรองผลลัพธ์เท่านั้น)")
+	}
+
+	if req.Mode == "" {
+		req.Mode = searchModeText
+	}
+	if req.Mode != searchModeText && req.Mode != searchModeVector && req.Mode != searchModeHybrid {
+		return req, http.StatusBadRequest, fmt.Errorf("mode ไม่ถูกต้อง: %s (text, vector, hybrid)", req.Mode)
+	}
+
+	// ทุกโหมดค้นหาเฉพาะเอกสารของ shop ที่ระบุ
+	shopID, err := resolveShopID(req.ShopID)
+	if err != nil {
+		return req, http.StatusBadRequest, err
+	}
+	req.ShopID = shopID
+
+	if req.MaxKeywords < 0 {
+		return req, http.StatusBadRequest, fmt.Errorf("maxKeywords ต้องไม่ติดลบ")
 	}
 
-	log.Printf("🔍 ค้นหาคำว่า: '%s'", req.Query)
+	if req.HighlightPreTag == "" && req.HighlightPostTag == "" {
+		req.HighlightPreTag, req.HighlightPostTag = cfg.HighlightPreTag, cfg.HighlightPostTag
+	}
 
-	// ใช้ Ollama ขยายคำค้นหา (แปลงภาษา, คำพ้องเสียง, แก้คำผิด, ทำนายคำ)
-	keywords := smartSearchKeywords(cfg, req.Query)
-	log.Printf("🧠 Ollama ขยายคำค้นหาได้ %d คำ: %v", len(keywords), keywords)
+	return req, http.StatusOK, nil
+}
 
-	// ค้นหาในโฟลเดอร์ doc/
-	docPath := "./doc"
+// executeSearch ค้นหาตาม mode แล้วจำกัดจำนวนผลลัพธ์
+func executeSearch(ctx context.Context, req SearchRequestSimple) ([]Match, error) {
+	log.Printf("🔍 ค้นหาคำว่า: '%s' (mode: %s, shopid: %s)", req.Query, req.Mode, req.ShopID)
 
-	// ⚡ ค้นหาทุกคำพร้อมกัน (Concurrent Search)
-	var allMatches []Match
-	var mu sync.Mutex
-	var wg sync.WaitGroup
+	threshold := cfg.VectorThreshold
+	if req.Threshold != nil {
+		threshold = *req.Threshold
+	}
+	vectorLimit := req.Limit
+	if vectorLimit <= 0 {
+		vectorLimit = cfg.VectorLimit
+	}
 
-	for _, keyword := range keywords {
-		wg.Add(1)
-		go func(kw string) {
-			defer wg.Done()
+	var uniqueMatches []Match
+	switch req.Mode {
+	case searchModeVector:
+		// vector search ใช้ข้อความไม่มีไวยากรณ์ แล้วกรองด้วยเงื่อนไขของคำค้นหา
+		q := parseQuery(req.Query)
+		matches, err := searchVectors(ctx, req.ShopID, q.plainText(), vectorLimit, threshold)
+		if err != nil {
+			log.Printf("❌ ค้นหา vector ไม่สำเร็จ: %v", err)
+			return nil, err
+		}
+		uniqueMatches = q.filterMatches(matches)
 
-			log.Printf("   🔎 ค้นหาคำ: '%s'", kw)
-			matches := searchInDirectory(docPath, "", kw, 3, 3) // 3 บรรทัดก่อน-หลัง
+	case searchModeHybrid:
+		uniqueMatches = hybridSearch(ctx, req.ShopID, req.Query, req.expansionOptions(), vectorLimit, threshold)
 
-			mu.Lock()
-			allMatches = append(allMatches, matches...)
-			mu.Unlock()
+	default:
+		uniqueMatches = textSearch(ctx, req.ShopID, req.Query, req.expansionOptions())
+	}
 
-			log.Printf("      พบ %d ผลลัพธ์", len(matches))
-		}(keyword)
+	// รวมผลลัพธ์ที่เนื้อหาซ้ำกัน (เช่น ย่อหน้าเดียวกันในหลายเอกสาร) ก่อนจำกัดจำนวน
+	candidates := cfg.DedupCandidates
+	if req.Limit > 0 && (candidates <= 0 || req.Limit*dedupLimitFactor < candidates) {
+		candidates = req.Limit * dedupLimitFactor
 	}
+	uniqueMatches = collapseNearDuplicates(uniqueMatches, cfg.DedupSimilarity, candidates)
 
-	// รอให้ทุก keyword ค้นหาเสร็จ
-	wg.Wait()
+	if req.Limit > 0 && len(uniqueMatches) > req.Limit {
+		uniqueMatches = uniqueMatches[:req.Limit]
+	}
+	return uniqueMatches, nil
+}
 
-	// ลบผลลัพธ์ซ้ำ
-	uniqueMatches := removeDuplicateMatches(allMatches)
-	log.Printf("📊 พบทั้งหมด %d ผลลัพธ์ (หลังลบซ้ำจาก %d)", len(uniqueMatches), len(allMatches))
+// toSearchResults แปลง matches เป็น SearchResultSimple format พร้อม highlight คำค้นหาทุกคำที่ขยายได้
+func toSearchResults(matches []Match, req SearchRequestSimple) []SearchResultSimple {
+	keywords := highlightKeywords(parseQuery(req.Query).highlightTerms(), matches)
 
-	// แปลง matches เป็น SearchResultSimple format
 	var results []SearchResultSimple
-	for _, match := range uniqueMatches {
+	for _, match := range matches {
 		// รวม context เป็น string เดียว
 		contextText := strings.Join(match.Context, "\n")
 
 		results = append(results, SearchResultSimple{
-			Content:  contextText,
-			Filename: filepath.Base(match.Filename),
-			LineNum:  match.LineNum,
+			Content:     contextText,
+			Highlighted: highlightText(req.ShopID, contextText, keywords, req.HighlightPreTag, req.HighlightPostTag),
+			Filename:    filepath.Base(match.Filename),
+			LineNum:     match.LineNum,
+			HitLines:    match.HitLines,
+			Path:        relativeDocPath(match.Filename),
+			HeadingPath: formatHeadingPath(match.HeadingPath),
+			Headings:    headingBreadcrumb(match.HeadingPath),
+			StartLine:   match.StartLine,
+			EndLine:     match.EndLine,
+			Keywords:    match.Keywords,
+			Hits:        contentHits(match),
+			Score:       match.Score,
+			Chunk:       match.Chunk,
+			Similarity:  match.Similarity,
+			Duplicates:  match.Duplicates,
 		})
 	}
+	return results
+}
 
-	// สร้างสรุปด้วย AI ถ้าต้องการ
-	var summary string
-	if req.UseSummary && len(uniqueMatches) > 0 {
-		log.Printf("🤖 กำลังสรุปผลด้วย AI...")
-		contextForAI := formatMatchesForAI(uniqueMatches, req.Query)
-		// ✨ เพิ่ม filename + line_number ให้ AI ได้ข้อมูลแหล่งที่มา
-		sourceInfo := buildSourceInfo(uniqueMatches)
-		summary = summarizeResultsSimple(contextForAI, req.Query, sourceInfo)
-		if summary != "" {
-			log.Printf("✅ สรุปด้วย AI สำเร็จ")
+// contentHits แปลงตำแหน่งในบรรทัดเป็น byte offset ใน content (context ที่ต่อด้วย "\n")
+func contentHits(match Match) []SearchHit {
+	var hits []SearchHit
+	for _, hit := range match.Hits {
+		row := hit.Line - match.StartLine
+		if row < 0 || row >= len(match.Context) {
+			continue
 		}
+		offset := 0
+		for _, line := range match.Context[:row] {
+			offset += len(line) + 1
+		}
+		hits = append(hits, SearchHit{
+			Keyword: hit.Keyword,
+			LineNum: hit.Line,
+			Start:   offset + hit.Start,
+			End:     offset + hit.End,
+		})
 	}
-
-	response := SearchResponseSimple{
-		Query:   req.Query,
-		Results: results,
-		Total:   len(results),
-		Summary: summary,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	json.NewEncoder(w).Encode(response)
+	return hits
 }
 
+// maxSummarySources จำนวนแหล่งที่มาสูงสุดที่แนบให้ AI และส่งเป็น citations
+const maxSummarySources = 10
+
 // buildSourceInfo สร้างข้อมูลแหล่งที่มา เพื่อให้ AI เหล่าว่ามาจากไหน
 func buildSourceInfo(matches []Match) string {
 	var builder strings.Builder
 	builder.WriteString("\n\n=== แหล่งที่มาของข้อมูล ===\n")
 
-	maxSources := 10
+	maxSources := maxSummarySources
 	for i, match := range matches {
 		if i >= maxSources {
 			break
@@ -176,27 +315,23 @@ func buildSourceInfo(matches []Match) string {
 	return builder.String()
 }
 
-// summarizeResultsSimple calls AI to summarize search results
-func summarizeResultsSimple(context, query, sourceInfo string) string {
+// summarizeResultsSimple ให้ LLM สรุปผลการค้นหา โดยลองตามลำดับ provider ที่ตั้งค่าไว้
+func summarizeResultsSimple(ctx context.Context, contextText, query, sourceInfo string) string {
 	// เพิ่มข้อมูลแหล่งที่มาให้ AI
-	fullContext := context + sourceInfo
-
-	// ลอง Gemini ก่อน
-	summary, err := summarizeWithGeminiText(cfg.GeminiAPIKey, fullContext, query)
-	if err == nil && summary != "" {
-		log.Printf("✅ ใช้ Gemini สรุปผลสำเร็จ")
-		return summary
-	}
+	prompt := buildSummaryPrompt(query, contextText+sourceInfo)
 
-	log.Printf("⚠️  Gemini ล้มเหลว, ลอง DeepSeek...")
+	for _, provider := range llmProviders {
+		providerCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.LLMTimeout)*time.Second)
+		summary, err := provider.Generate(providerCtx, prompt)
+		cancel()
 
-	// ถ้า Gemini ล้มเหลว ลอง DeepSeek
-	summary, err = summarizeWithDeepSeekText(cfg.DeepSeekAPIKey, fullContext, query)
-	if err == nil && summary != "" {
-		log.Printf("✅ ใช้ DeepSeek สรุปผลสำเร็จ")
-		return summary
+		if err == nil && strings.TrimSpace(summary) != "" {
+			log.Printf("✅ ใช้ %s สรุปผลสำเร็จ", provider.Name())
+			return summary
+		}
+		log.Printf("⚠️  %s ล้มเหลว: %v", provider.Name(), err)
 	}
 
-	log.Printf("❌ ทั้ง Gemini และ DeepSeek ล้มเหลว")
+	log.Printf("❌ LLM ทุกตัวล้มเหลว (%d providers)", len(llmProviders))
 	return fmt.Sprintf("พบผลลัพธ์ที่เกี่ยวข้องกับ '%s'", query)
 }
@@ -0,0 +1,84 @@
+package main
+
+import (
+	"html"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// highlightText ครอบทุกตำแหน่งที่เจอคำค้นหาด้วย preTag/postTag (ไม่สนตัวพิมพ์)
+// คำค้นหาภาษาไทยที่ไม่พบทั้งคำ จะ highlight คำย่อยที่ได้จากการตัดคำแทน
+// เพราะภาษาไทยเขียนติดกันและคำอาจอยู่ในคำประสม
+// ข้อความจากเอกสารถูก escape เป็น HTML เสมอ (มีแค่ preTag/postTag ที่ไม่ escape) กัน markdown ที่มี <script> ไปรันที่ client
+func highlightText(shopID, text string, keywords []string, preTag, postTag string) string {
+	var ranges [][2]int
+	for _, keyword := range keywords {
+		keyword = strings.TrimSpace(keyword)
+		if keyword == "" {
+			continue
+		}
+
+		hits := findKeywordHits(text, keyword)
+		if len(hits) == 0 && hasThaiCharacters(keyword) {
+			for _, tok := range tokenizeLine(queryTokenizer, shopID, keyword) {
+				// ข้ามคำย่อยตัวเดียว เช่น "ๆ" ที่จะทำให้ highlight ทั้งเอกสาร
+				if utf8.RuneCountInString(tok.Term) >= 2 {
+					hits = append(hits, findKeywordHits(text, tok.Term)...)
+				}
+			}
+		}
+		ranges = append(ranges, hits...)
+	}
+	if len(ranges) == 0 {
+		return html.EscapeString(text)
+	}
+
+	// รวมช่วงที่ซ้อนหรือติดกัน เพื่อไม่ให้ tag ซ้อนกัน
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	merged := [][2]int{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r[0] <= last[1] {
+			last[1] = max(last[1], r[1])
+			continue
+		}
+		merged = append(merged, r)
+	}
+
+	var builder strings.Builder
+	prev := 0
+	for _, r := range merged {
+		builder.WriteString(html.EscapeString(text[prev:r[0]]))
+		builder.WriteString(preTag)
+		builder.WriteString(html.EscapeString(text[r[0]:r[1]]))
+		builder.WriteString(postTag)
+		prev = r[1]
+	}
+	builder.WriteString(html.EscapeString(text[prev:]))
+	return builder.String()
+}
+
+// highlightKeywords คำค้นหาที่ใช้ highlight: คำที่ขยายได้จากทุกผลลัพธ์ รวมกับคำค้นหาเดิม
+func highlightKeywords(queryTerms []string, matches []Match) []string {
+	seen := map[string]bool{}
+	var keywords []string
+	add := func(keyword string) {
+		key := strings.ToLower(strings.TrimSpace(keyword))
+		if key == "" || seen[key] {
+			return
+		}
+		seen[key] = true
+		keywords = append(keywords, keyword)
+	}
+
+	for _, term := range queryTerms {
+		add(term)
+	}
+	for _, match := range matches {
+		for _, keyword := range match.Keywords {
+			add(keyword)
+		}
+	}
+	return keywords
+}
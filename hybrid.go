@@ -0,0 +1,110 @@
+package main
+
+import (
+	"context"
+	"log"
+	"path/filepath"
+	"sort"
+	"sync"
+)
+
+// rrfK ค่าคงที่ของ reciprocal rank fusion (ค่ามาตรฐาน 60)
+const rrfK = 60.0
+
+// hybridSearch ค้นหาแบบข้อความและ vector พร้อมกัน แล้วรวมผลด้วย reciprocal rank fusion
+func hybridSearch(ctx context.Context, shopID, query string, opts expansionOptions, limit int, threshold float64) []Match {
+	var textMatches, vectorMatches []Match
+	var wg sync.WaitGroup
+	q := parseQuery(query)
+
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		textMatches = textSearch(ctx, shopID, query, opts)
+	}()
+	go func() {
+		defer wg.Done()
+		matches, err := searchVectors(ctx, shopID, q.plainText(), limit, threshold)
+		if err != nil {
+			// vector ใช้ไม่ได้ก็ยังคืนผลจากข้อความ
+			log.Printf("⚠️  hybrid: ค้นหา vector ไม่สำเร็จ ใช้เฉพาะผลจากข้อความ: %v", err)
+			return
+		}
+		vectorMatches = q.filterMatches(matches)
+	}()
+	wg.Wait()
+
+	fused := fuseRRF(textMatches, vectorMatches, cfg.HybridTextWeight, cfg.HybridVectorWeight)
+	log.Printf("🔀 hybrid: รวม %d (ข้อความ) + %d (vector) → %d ผลลัพธ์", len(textMatches), len(vectorMatches), len(fused))
+	return fused
+}
+
+// fuseRRF รวมผลสองชุดด้วย RRF: score = Σ weight / (k + rank)
+// ผลจากข้อความที่บรรทัดอยู่ในช่วงบรรทัดของ chunk จากไฟล์เดียวกัน จะถูกรวมเป็นผลเดียว
+func fuseRRF(textMatches, vectorMatches []Match, textWeight, vectorWeight float64) []Match {
+	type fusedGroup struct {
+		match     Match
+		textRank  int // 0 = ไม่มี
+		vectorHit bool
+		score     float64
+		// ไฟล์และช่วงบรรทัดของ chunk (เริ่มที่ 1) คงเดิมแม้ match จะถูกแทนด้วยผลจากข้อความ
+		vectorFile         string
+		startLine, endLine int
+	}
+
+	var groups []*fusedGroup
+	for rank, vm := range vectorMatches {
+		vm.Score = vectorWeight / (rrfK + float64(rank+1))
+		start, end := vm.StartLine, vm.EndLine
+		if end < start || start == 0 {
+			start, end = vm.LineNum, vm.LineNum+len(vm.Context)-1
+		}
+		groups = append(groups, &fusedGroup{match: vm, vectorHit: true, score: vm.Score, vectorFile: vm.Filename, startLine: start, endLine: end})
+	}
+
+	for rank, tm := range textMatches {
+		score := textWeight / (rrfK + float64(rank+1))
+
+		var target *fusedGroup
+		for _, g := range groups {
+			if g.vectorHit && tm.LineNum >= g.startLine && tm.LineNum <= g.endLine &&
+				sameDocument(tm.Filename, g.vectorFile) {
+				target = g
+				break
+			}
+		}
+
+		if target == nil {
+			tm.Score = score
+			groups = append(groups, &fusedGroup{match: tm, textRank: rank + 1, score: score})
+			continue
+		}
+
+		// ใช้เฉพาะอันดับที่ดีที่สุดของข้อความต่อ chunk และแสดงผลเป็นบรรทัดที่เจอคำค้นหา
+		if target.textRank == 0 {
+			target.textRank = rank + 1
+			target.score += score
+
+			chunk, similarity := target.match.Chunk, target.match.Similarity
+			target.match = tm
+			target.match.Chunk = chunk
+			target.match.Similarity = similarity
+		}
+	}
+
+	fused := make([]Match, 0, len(groups))
+	for _, g := range groups {
+		g.match.Score = g.score
+		fused = append(fused, g.match)
+	}
+	sort.SliceStable(fused, func(i, j int) bool {
+		return fused[i].Score > fused[j].Score
+	})
+	return fused
+}
+
+// sameDocument เทียบไฟล์จาก index (path เต็ม) กับชื่อไฟล์ใน documents (relative กับโฟลเดอร์ของ shop)
+func sameDocument(indexPath, filename string) bool {
+	_, rel := splitDocPath(cfg.DocPath, indexPath)
+	return filepath.ToSlash(rel) == filepath.ToSlash(filepath.Clean(filename))
+}
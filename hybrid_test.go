@@ -0,0 +1,86 @@
+package main
+
+import (
+	"math"
+	"testing"
+)
+
+func TestFuseRRF(t *testing.T) {
+	defer func(old *Config) { cfg = old }(cfg)
+	cfg = &Config{DocPath: "/docs", DefaultShopID: "shop001"}
+
+	chunk := Match{Filename: "a.md", LineNum: 10, StartLine: 10, EndLine: 20, Context: make([]string, 11), Chunk: 3, Similarity: 0.8}
+	textHit := func(file string, line int) Match {
+		return Match{Filename: "/docs/shop001/" + file, LineNum: line, StartLine: line, EndLine: line, Context: []string{"x"}}
+	}
+
+	type result struct {
+		file  string
+		line  int
+		chunk int
+	}
+	tests := []struct {
+		name   string
+		text   []Match
+		vector []Match
+		want   []result
+	}{
+		{
+			name:   "ไม่มีผลซ้อนกัน",
+			text:   []Match{textHit("b.md", 5)},
+			vector: []Match{chunk},
+			want:   []result{{"a.md", 10, 3}, {"/docs/shop001/b.md", 5, 0}},
+		},
+		{
+			name:   "ผลจากข้อความ 2 ตัวใน chunk เดียวกัน",
+			text:   []Match{textHit("a.md", 12), textHit("a.md", 20)},
+			vector: []Match{chunk},
+			want:   []result{{"/docs/shop001/a.md", 12, 3}},
+		},
+		{
+			name:   "บรรทัดแรกของ chunk และนอก chunk",
+			text:   []Match{textHit("a.md", 10), textHit("a.md", 21)},
+			vector: []Match{chunk},
+			want:   []result{{"/docs/shop001/a.md", 10, 3}, {"/docs/shop001/a.md", 21, 0}},
+		},
+		{
+			name:   "ไฟล์อื่นที่บรรทัดเดียวกัน",
+			text:   []Match{textHit("c.md", 12)},
+			vector: []Match{chunk},
+			want:   []result{{"a.md", 10, 3}, {"/docs/shop001/c.md", 12, 0}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fused := fuseRRF(tt.text, tt.vector, 1, 1)
+			if len(fused) != len(tt.want) {
+				t.Fatalf("fuseRRF ได้ %d ผลลัพธ์, want %d: %+v", len(fused), len(tt.want), fused)
+			}
+			for i, want := range tt.want {
+				got := result{fused[i].Filename, fused[i].LineNum, fused[i].Chunk}
+				if got != want {
+					t.Errorf("ผลที่ %d = %+v, want %+v", i, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestFuseRRFScore(t *testing.T) {
+	defer func(old *Config) { cfg = old }(cfg)
+	cfg = &Config{DocPath: "/docs", DefaultShopID: "shop001"}
+
+	chunk := Match{Filename: "a.md", LineNum: 1, StartLine: 1, EndLine: 5, Context: make([]string, 5)}
+	text := []Match{
+		{Filename: "/docs/shop001/a.md", LineNum: 2},
+		{Filename: "/docs/shop001/a.md", LineNum: 3},
+	}
+
+	fused := fuseRRF(text, []Match{chunk}, 0.5, 0.5)
+	// ใช้เฉพาะอันดับที่ดีที่สุดของข้อความต่อ chunk
+	want := 0.5/(rrfK+1) + 0.5/(rrfK+1)
+	if len(fused) != 1 || math.Abs(fused[0].Score-want) > 1e-9 {
+		t.Errorf("fuseRRF = %+v, want 1 ผลลัพธ์ score %.6f", fused, want)
+	}
+}
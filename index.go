@@ -0,0 +1,595 @@
+package main
+
+import (
+	"bufio"
+	"encoding/gob"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// indexFormatVersion เปลี่ยนเมื่อโครงสร้าง index เปลี่ยน เพื่อบังคับให้สร้างใหม่
+const indexFormatVersion = 10
+
+// Posting ตำแหน่งของคำหนึ่งคำใน index
+type Posting struct {
+	Doc  int // id ของไฟล์
+	Line int // บรรทัด (เริ่มที่ 0)
+	Pos  int // byte offset ของคำในบรรทัด
+}
+
+// indexedDoc ข้อมูลไฟล์ที่ถูก index ไว้
+type indexedDoc struct {
+	Path       string
+	ShopID     string // เจ้าของเอกสาร (ดู tenant.go)
+	ModTime    time.Time
+	Size       int64
+	Lines      []string
+	Lengths    []int           // จำนวน term ในแต่ละบรรทัด (ใช้ normalize คะแนน BM25)
+	Terms      []string        // คำทั้งหมดในไฟล์ (ใช้ตอนลบ postings)
+	Blocks     []markdownBlock // โครงสร้าง markdown (ดู markdown.go)
+	LineBlocks []int           // บรรทัด → index ของ block
+	Translit   map[string]int  // "โครงเสียง\tคำ" → จำนวนครั้ง (ดู transliterate.go)
+	Dictionary string          // signature ของคำศัพท์ตัดคำที่ใช้ (เปลี่ยน = ต้องตัดคำใหม่)
+}
+
+// indexSnapshot รูปแบบที่บันทึกลง disk
+type indexSnapshot struct {
+	Version   int
+	Segmenter string
+	NextID    int
+	Docs      map[int]*indexedDoc
+	Postings  map[string][]Posting
+}
+
+// InvertedIndex เก็บ term → postings ของเอกสาร markdown ทั้งหมด
+type InvertedIndex struct {
+	mu       sync.RWMutex
+	root     string
+	file     string
+	nextID   int
+	docs     map[int]*indexedDoc
+	byPath   map[string]int
+	postings map[string][]Posting
+	translit map[string]map[string]map[string]int // shop → โครงเสียง → คำทับศัพท์ในเอกสาร → จำนวนครั้ง
+	fuzzy    *fuzzyVocabulary                     // สำหรับหาคำที่สะกดผิด (ดู fuzzy.go)
+	grams    *termGrams                           // สำหรับหา term ที่มีคำค้นหาเป็นส่วนหนึ่ง (ดู termgrams.go)
+}
+
+// indexToken คำที่ได้จากการตัดบรรทัด พร้อมตำแหน่ง
+type indexToken struct {
+	Term string
+	Pos  int
+}
+
+type lineKey struct {
+	Doc  int
+	Line int
+}
+
+var docIndex *InvertedIndex
+
+func newInvertedIndex(root, file string) *InvertedIndex {
+	return &InvertedIndex{
+		root:     root,
+		file:     file,
+		docs:     make(map[int]*indexedDoc),
+		byPath:   make(map[string]int),
+		postings: make(map[string][]Posting),
+		translit: make(map[string]map[string]map[string]int),
+		fuzzy:    newFuzzyVocabulary(),
+		grams:    newTermGrams(),
+	}
+}
+
+// load โหลด index จาก disk (ถ้ารูปแบบหรือวิธีตัดคำไม่ตรง จะไม่โหลดและสร้างใหม่ทั้งหมด)
+func (idx *InvertedIndex) load() error {
+	f, err := os.Open(idx.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var snap indexSnapshot
+	if err := gob.NewDecoder(bufio.NewReader(f)).Decode(&snap); err != nil {
+		return err
+	}
+	if snap.Version != indexFormatVersion || snap.Segmenter != segmenterName() {
+		return fmt.Errorf("index version %d/%s ไม่ตรงกับ %d/%s", snap.Version, snap.Segmenter, indexFormatVersion, segmenterName())
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	idx.nextID = snap.NextID
+	idx.docs = snap.Docs
+	idx.postings = snap.Postings
+	idx.fuzzy = newFuzzyVocabulary()
+	idx.grams = newTermGrams()
+	for term := range idx.postings {
+		idx.fuzzy.add(term)
+		idx.grams.add(term)
+	}
+	idx.byPath = make(map[string]int, len(snap.Docs))
+	for id, doc := range snap.Docs {
+		idx.byPath[doc.Path] = id
+		doc.ShopID, _ = splitDocPath(idx.root, doc.Path) // DefaultShopID อาจเปลี่ยน
+	}
+	// คำทับศัพท์แยกตาม shop สร้างจากเอกสารใหม่ทุกครั้ง (shop ของเอกสารอาจเปลี่ยนตามด้านบน)
+	idx.translit = make(map[string]map[string]map[string]int)
+	for _, doc := range snap.Docs {
+		idx.addTranslitLocked(doc)
+	}
+	return nil
+}
+
+// save บันทึก index ลง disk (เขียนไฟล์ชั่วคราวแล้ว rename)
+func (idx *InvertedIndex) save() error {
+	if err := os.MkdirAll(filepath.Dir(idx.file), 0755); err != nil {
+		return err
+	}
+
+	tmp := idx.file + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+
+	idx.mu.RLock()
+	snap := indexSnapshot{
+		Version:   indexFormatVersion,
+		Segmenter: segmenterName(),
+		NextID:    idx.nextID,
+		Docs:      idx.docs,
+		Postings:  idx.postings,
+	}
+	w := bufio.NewWriter(f)
+	err = gob.NewEncoder(w).Encode(&snap)
+	idx.mu.RUnlock()
+
+	if err == nil {
+		err = w.Flush()
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, idx.file)
+}
+
+// refresh สแกนโฟลเดอร์เอกสาร แล้ว index ใหม่เฉพาะไฟล์ที่เพิ่ม/แก้ไข/ลบ
+func (idx *InvertedIndex) refresh() (int, error) {
+	seen := make(map[string]bool)
+	changed := 0
+
+	err := filepath.Walk(idx.root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() || !strings.HasSuffix(strings.ToLower(path), ".md") {
+			return nil
+		}
+		seen[path] = true
+
+		idx.mu.RLock()
+		id, exists := idx.byPath[path]
+		upToDate := exists && idx.docs[id].ModTime.Equal(info.ModTime()) && idx.docs[id].Size == info.Size() &&
+			idx.docs[id].Dictionary == segmentDict.signature(idx.docs[id].ShopID)
+		idx.mu.RUnlock()
+		if upToDate {
+			return nil
+		}
+
+		if err := idx.indexFile(path, info); err != nil {
+			log.Printf("⚠️  index ไฟล์ %s ไม่สำเร็จ: %v", path, err)
+			return nil
+		}
+		changed++
+		return nil
+	})
+	if err != nil {
+		return changed, err
+	}
+
+	// ลบไฟล์ที่ไม่มีอยู่แล้ว
+	idx.mu.Lock()
+	for path, id := range idx.byPath {
+		if !seen[path] {
+			idx.removeDocLocked(id)
+			changed++
+		}
+	}
+	idx.mu.Unlock()
+
+	return changed, nil
+}
+
+// indexFile อ่านไฟล์แล้วแทนที่ postings เดิมของไฟล์นั้น
+func (idx *InvertedIndex) indexFile(path string, info os.FileInfo) error {
+	lines, err := readLines(path)
+	if err != nil {
+		return err
+	}
+
+	// ตัดคำนอก lock เพราะใช้เวลานาน (ใช้คำศัพท์ของ shop เจ้าของไฟล์)
+	shopID, _ := splitDocPath(idx.root, path)
+	dictionary := segmentDict.signature(shopID)
+	postings := make(map[string][]Posting)
+	lengths := make([]int, len(lines))
+	translit := make(map[string]int)
+	blocks := parseMarkdownBlocks(lines)
+	lineBlocks := lineBlockIndex(len(lines), blocks)
+	for i, line := range lines {
+		// index จากข้อความที่ normalize แล้ว (ตำแหน่งใน Posting จึงเป็นตำแหน่งหลัง normalize)
+		line = normalizeText(line)
+		tok := contentTokenizer
+		if b := lineBlocks[i]; b >= 0 && blocks[b].Kind == blockHeading {
+			tok = headingTokenizer
+		}
+		tokens := tokenizeLine(tok, shopID, line)
+		lengths[i] = len(tokens)
+
+		// คำทับศัพท์ต้องตัดแบบเดียวกับคำค้นหา จึงจะเทียบ surface กันได้
+		surfaceTokens := tokens
+		if tok.Name() != queryTokenizer.Name() {
+			surfaceTokens = tokenizeLine(queryTokenizer, shopID, line)
+		}
+		for key, count := range translitSurfaces(line, surfaceTokens) {
+			translit[key] += count
+		}
+		for _, t := range tokens {
+			postings[t.Term] = append(postings[t.Term], Posting{Line: i, Pos: t.Pos})
+		}
+	}
+
+	terms := make([]string, 0, len(postings))
+	for term := range postings {
+		terms = append(terms, term)
+	}
+
+	idx.mu.Lock()
+	defer idx.mu.Unlock()
+
+	if oldID, exists := idx.byPath[path]; exists {
+		idx.removeDocLocked(oldID)
+	}
+
+	id := idx.nextID
+	idx.nextID++
+	idx.docs[id] = &indexedDoc{
+		Path:       path,
+		ShopID:     shopID,
+		ModTime:    info.ModTime(),
+		Size:       info.Size(),
+		Lines:      lines,
+		Lengths:    lengths,
+		Terms:      terms,
+		Blocks:     blocks,
+		LineBlocks: lineBlocks,
+		Translit:   translit,
+		Dictionary: dictionary,
+	}
+	idx.byPath[path] = id
+	idx.addTranslitLocked(idx.docs[id])
+
+	for term, list := range postings {
+		if len(idx.postings[term]) == 0 {
+			idx.fuzzy.add(term)
+			idx.grams.add(term)
+		}
+		for _, p := range list {
+			p.Doc = id
+			idx.postings[term] = append(idx.postings[term], p)
+		}
+	}
+	return nil
+}
+
+// removeDocLocked ลบไฟล์ออกจาก index (ต้องถือ lock อยู่แล้ว)
+func (idx *InvertedIndex) removeDocLocked(id int) {
+	doc, exists := idx.docs[id]
+	if !exists {
+		return
+	}
+
+	for _, term := range doc.Terms {
+		list := idx.postings[term]
+		kept := list[:0]
+		for _, p := range list {
+			if p.Doc != id {
+				kept = append(kept, p)
+			}
+		}
+		if len(kept) == 0 {
+			delete(idx.postings, term)
+			idx.fuzzy.remove(term)
+			idx.grams.remove(term)
+		} else {
+			idx.postings[term] = kept
+		}
+	}
+
+	idx.removeTranslitLocked(doc)
+	delete(idx.byPath, doc.Path)
+	delete(idx.docs, id)
+}
+
+// addTranslitLocked เพิ่มคำทับศัพท์ของเอกสารเข้าตารางของ shop (ต้องถือ lock อยู่แล้ว)
+func (idx *InvertedIndex) addTranslitLocked(doc *indexedDoc) {
+	if len(doc.Translit) == 0 {
+		return
+	}
+	shop := idx.translit[doc.ShopID]
+	if shop == nil {
+		shop = make(map[string]map[string]int)
+		idx.translit[doc.ShopID] = shop
+	}
+	for key, count := range doc.Translit {
+		skeleton, surface := splitTranslitKey(key)
+		if shop[skeleton] == nil {
+			shop[skeleton] = make(map[string]int)
+		}
+		shop[skeleton][surface] += count
+	}
+}
+
+// removeTranslitLocked ลบคำทับศัพท์ของเอกสารออกจากตารางของ shop (ต้องถือ lock อยู่แล้ว)
+func (idx *InvertedIndex) removeTranslitLocked(doc *indexedDoc) {
+	shop := idx.translit[doc.ShopID]
+	for key, count := range doc.Translit {
+		skeleton, surface := splitTranslitKey(key)
+		if shop[skeleton][surface] -= count; shop[skeleton][surface] <= 0 {
+			delete(shop[skeleton], surface)
+		}
+		if len(shop[skeleton]) == 0 {
+			delete(shop, skeleton)
+		}
+	}
+	if len(shop) == 0 {
+		delete(idx.translit, doc.ShopID)
+	}
+}
+
+// watch ตรวจสอบการเปลี่ยนแปลงของไฟล์เป็นระยะ แล้วบันทึก index เมื่อมีการเปลี่ยน
+func (idx *InvertedIndex) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		changed, err := idx.refresh()
+		if err != nil {
+			log.Printf("⚠️  อัปเดต index ไม่สำเร็จ: %v", err)
+			continue
+		}
+		if changed == 0 {
+			continue
+		}
+
+		log.Printf("🔄 อัปเดต index: %d ไฟล์มีการเปลี่ยนแปลง", changed)
+		if err := idx.save(); err != nil {
+			log.Printf("⚠️  บันทึก index ไม่สำเร็จ: %v", err)
+		}
+	}
+}
+
+// search หาบรรทัดที่มีคำค้นหา (substring หลัง normalize ทั้งสองฝั่ง) เฉพาะเอกสารของ shopID
+// โดยใช้ index เลือกบรรทัดที่เป็นไปได้ก่อน context ของแต่ละผลคือ block markdown ที่ครอบบรรทัดนั้น
+func (idx *InvertedIndex) search(shopID, searchWord string) []Match {
+	word := strings.TrimSpace(normalizeText(searchWord))
+	if word == "" {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	candidates := idx.candidateLinesLocked(shopID, word)
+
+	keys := make([]lineKey, 0, len(candidates))
+	for key := range candidates {
+		keys = append(keys, key)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		a, b := idx.docs[keys[i].Doc], idx.docs[keys[j].Doc]
+		if a.Path != b.Path {
+			return a.Path < b.Path
+		}
+		return keys[i].Line < keys[j].Line
+	})
+
+	var matches []Match
+	for _, key := range keys {
+		doc := idx.docs[key.Doc]
+		if doc.ShopID != shopID {
+			continue
+		}
+		hits := findKeywordHits(doc.Lines[key.Line], word)
+		if len(hits) == 0 {
+			continue
+		}
+		start, end, headingPath := blockContextRange(doc.Blocks, doc.LineBlocks, key.Line, cfg.ContextMaxLines, cfg.ContextLines)
+		match := newMatch(doc.Path, doc.Lines, key.Line, start, end)
+		match.HeadingPath = headingPath
+		match.Keywords = []string{searchWord}
+		for _, hit := range hits {
+			match.Hits = append(match.Hits, Hit{Keyword: searchWord, Line: key.Line + 1, Start: hit[0], End: hit[1]})
+		}
+		match.LineTokens = doc.Lengths[key.Line]
+		matches = append(matches, match)
+	}
+
+	return matches
+}
+
+// headingPathAt คืน heading path ของบรรทัด (เริ่มที่ 0) ในไฟล์ของ shopID ที่ชื่อสัมพัทธ์ตรงกับ filename
+func (idx *InvertedIndex) headingPathAt(shopID, filename string, line int) []string {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	for _, doc := range idx.docs {
+		if doc.ShopID != shopID {
+			continue
+		}
+		if _, rel := splitDocPath(idx.root, doc.Path); rel != filepath.Clean(filepath.FromSlash(filename)) {
+			continue
+		}
+		_, _, headingPath := blockContextRange(doc.Blocks, doc.LineBlocks, line, 1, 0)
+		return headingPath
+	}
+	return nil
+}
+
+// corpusStats สถิติของเอกสารของ shop หนึ่งสำหรับคำนวณ BM25 (1 บรรทัดที่มีเนื้อหา = 1 เอกสาร)
+type corpusStats struct {
+	Lines     int
+	AvgLength float64
+}
+
+func (idx *InvertedIndex) stats(shopID string) corpusStats {
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var lines, tokens int
+	for _, doc := range idx.docs {
+		if doc.ShopID != shopID {
+			continue
+		}
+		for _, n := range doc.Lengths {
+			if n > 0 {
+				lines++
+				tokens += n
+			}
+		}
+	}
+
+	stats := corpusStats{Lines: lines}
+	if lines > 0 {
+		stats.AvgLength = float64(tokens) / float64(lines)
+	}
+	return stats
+}
+
+// maxSplitPieceClusters คำย่อยที่หาไม่เจอและยาวไม่เกินจำนวน TCC นี้จึงแยกหาทีละ TCC
+const maxSplitPieceClusters = 6
+
+// candidateLinesLocked คืนบรรทัดที่อาจมีคำค้นหา ตัดคำค้นหาด้วย tokenizer เดียวกับตอน index
+// (เนื้อหาและหัวข้ออาจใช้คนละ tokenizer จึงรวมผลของทั้งสองแบบ)
+func (idx *InvertedIndex) candidateLinesLocked(shopID, word string) map[lineKey]bool {
+	result := idx.candidateLinesWithLocked(contentTokenizer, shopID, word)
+	if headingTokenizer.Name() == contentTokenizer.Name() {
+		return result
+	}
+	for key := range idx.candidateLinesWithLocked(headingTokenizer, shopID, word) {
+		if result == nil {
+			result = make(map[lineKey]bool)
+		}
+		result[key] = true
+	}
+	return result
+}
+
+// candidateLinesWithLocked ทุกคำย่อยของคำค้นหา (ตัดด้วย tok) ต้องอยู่ในบาง term ของบรรทัดนั้น
+func (idx *InvertedIndex) candidateLinesWithLocked(tok Tokenizer, shopID, word string) map[lineKey]bool {
+	var pieces []string
+	for _, t := range tokenizeLine(tok, shopID, word) {
+		pieces = append(pieces, t.Term)
+	}
+
+	// คำค้นหาที่ไม่มีตัวอักษร/ตัวเลขเลย → ตรวจทุกบรรทัดในหน่วยความจำ
+	if len(pieces) == 0 {
+		all := make(map[lineKey]bool)
+		for id, doc := range idx.docs {
+			if doc.ShopID != shopID {
+				continue
+			}
+			for i := range doc.Lines {
+				all[lineKey{id, i}] = true
+			}
+		}
+		return all
+	}
+
+	var result map[lineKey]bool
+	for _, piece := range pieces {
+		lines := idx.linesContainingLocked(shopID, piece)
+
+		// คำย่อยภาษาไทยอาจคร่อมรอยต่อของ term ในเอกสาร (ตัดคำคนละแบบ) → ใช้ทีละ TCC แทน
+		// เฉพาะคำย่อยที่สั้นพอ เพราะ TCC สั้นๆ อยู่ในบรรทัดเกือบทั้งหมด
+		if len(lines) == 0 && hasThaiCharacters(piece) {
+			if clusters := thaiCharacterClusters(piece); len(clusters) > 1 && len(clusters) <= maxSplitPieceClusters {
+				var byCluster map[lineKey]bool
+				for _, c := range clusters {
+					if byCluster = intersectLines(byCluster, idx.linesContainingLocked(shopID, c.Term)); len(byCluster) == 0 {
+						break
+					}
+				}
+				lines = byCluster
+			}
+		}
+
+		result = intersectLines(result, lines)
+		if len(result) == 0 {
+			return nil
+		}
+	}
+	return result
+}
+
+// linesContainingLocked รวม postings (เฉพาะของ shopID) ของ term ที่ตรงกับ piece และทุก term ที่มี piece เป็นส่วนหนึ่ง
+func (idx *InvertedIndex) linesContainingLocked(shopID, piece string) map[lineKey]bool {
+	lines := make(map[lineKey]bool)
+	add := func(term string) {
+		for _, p := range idx.postings[term] {
+			if idx.docs[p.Doc].ShopID == shopID {
+				lines[lineKey{p.Doc, p.Line}] = true
+			}
+		}
+	}
+
+	add(piece)
+	for _, term := range idx.grams.containing(piece) {
+		if term != piece {
+			add(term)
+		}
+	}
+	return lines
+}
+
+// intersectLines หา intersection (nil หมายถึงยังไม่มีเงื่อนไข)
+func intersectLines(a, b map[lineKey]bool) map[lineKey]bool {
+	if a == nil {
+		return b
+	}
+	result := make(map[lineKey]bool)
+	for key := range a {
+		if b[key] {
+			result[key] = true
+		}
+	}
+	return result
+}
+
+// readLines อ่านไฟล์เป็นรายบรรทัด
+func readLines(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var lines []string
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+	}
+	return lines, scanner.Err()
+}
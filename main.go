@@ -3,16 +3,21 @@ package main
 import (
 	"log"
 	"net/http"
+	"os"
+	"time"
 )
 
 var cfg *Config
 
+// llmProviders ลำดับ provider ที่ใช้สรุปผล (ตัวแรกที่สำเร็จจะถูกใช้)
+var llmProviders []LLMProvider
+
 func main() {
 	// โหลด config
 	cfg = loadConfig()
 
 	log.Println("🚀 เริ่มต้น Simple Text Search API Server")
-	log.Println("📁 ค้นหาในโฟลเดอร์: ./doc")
+	log.Printf("📁 ค้นหาในโฟลเดอร์: %s", cfg.DocPath)
 
 	// ✨ โหลด word segmentation library (mapkha)
 	if err := initWordSegmentation(); err != nil {
@@ -20,14 +25,115 @@ func main() {
 		log.Println("    → ยังคงทำงานต่อได้ แต่ค้นหาจะไม่มี Thai word segmentation")
 	}
 
+	// 🤖 เตรียม LLM providers สำหรับสรุปผล
+	apiHTTPClient.Timeout = time.Duration(cfg.LLMTimeout) * time.Second
+	llmProviders = newLLMProviders(cfg)
+	var providerNames []string
+	for _, provider := range llmProviders {
+		providerNames = append(providerNames, provider.Name())
+	}
+	log.Printf("🤖 LLM providers: %v", providerNames)
+
+	// 📚 โหลด/สร้าง inverted index ของเอกสาร
+	initDocIndex()
+
+	// 🧠 cache ผลการขยายคำค้นหา
+	initExpansionCache()
+
+	// 📖 พจนานุกรมคำพ้อง (ใช้ได้แม้ Ollama ล่ม)
+	initSynonyms()
+
+	// 🗄️ เชื่อมต่อ PostgreSQL สำหรับ vector data
+	if err := initDatabase(cfg); err != nil {
+		log.Printf("⚠️  เชื่อมต่อฐานข้อมูลไม่ได้: %v", err)
+		log.Println("    → ยังค้นหาแบบข้อความได้ แต่ /build จะใช้งานไม่ได้")
+	}
+
 	// Routes
 	http.HandleFunc("/health", healthHandlerSimple)
 	http.HandleFunc("/search", searchHandlerSimple)
+	http.HandleFunc("/search/stream", streamSearchHandler)
+	http.HandleFunc("/build", buildHandler)
+	http.HandleFunc("/admin/expansion-cache", expansionCacheHandler)
+	http.HandleFunc("/admin/synonyms", synonymsHandler)
+	http.HandleFunc("/admin/dictionary", dictionaryHandler)
+	http.HandleFunc("/admin/segment", segmentPreviewHandler)
 
 	log.Println("✅ เปิดใช้งาน HTTP server ที่พอร์ต 8080")
 	log.Println("  POST http://localhost:8080/search")
+	log.Println("  POST http://localhost:8080/search/stream (SSE)")
+	log.Println("  POST http://localhost:8080/build")
+	log.Println("  GET/DELETE http://localhost:8080/admin/expansion-cache")
+	log.Println("  GET/POST/DELETE http://localhost:8080/admin/synonyms")
+	log.Println("  GET/POST/DELETE http://localhost:8080/admin/dictionary")
+	log.Println("  GET http://localhost:8080/admin/segment?text=")
 
 	if err := http.ListenAndServe(":8080", nil); err != nil {
 		log.Fatal(err)
 	}
 }
+
+// initDocIndex โหลด index จาก disk แล้วอัปเดตเฉพาะไฟล์ที่เปลี่ยน จากนั้นเฝ้าดูการเปลี่ยนแปลง
+func initDocIndex() {
+	docIndex = newInvertedIndex(cfg.DocPath, cfg.IndexPath)
+
+	if err := docIndex.load(); err != nil {
+		log.Printf("⚠️  โหลด index จาก %s ไม่ได้ (%v) → สร้างใหม่", cfg.IndexPath, err)
+	}
+
+	start := time.Now()
+	changed, err := docIndex.refresh()
+	if err != nil {
+		log.Printf("⚠️  สร้าง index ไม่สำเร็จ: %v", err)
+	}
+	log.Printf("✅ Index พร้อมใช้งาน (%d ไฟล์อัปเดต, %v)", changed, time.Since(start))
+
+	if changed > 0 {
+		if err := docIndex.save(); err != nil {
+			log.Printf("⚠️  บันทึก index ไม่สำเร็จ: %v", err)
+		}
+	}
+
+	if cfg.IndexRefresh > 0 {
+		go docIndex.watch(time.Duration(cfg.IndexRefresh) * time.Second)
+	}
+}
+
+// initExpansionCache สร้าง cache ผลการขยายคำค้นหา โหลดจาก disk แล้วบันทึกเป็นระยะ
+func initExpansionCache() {
+	expansionCache = newQueryExpansionCache(cfg.ExpansionCacheSize, time.Duration(cfg.ExpansionCacheTTL)*time.Second, cfg.ExpansionCachePath)
+	if cfg.ExpansionCacheSize <= 0 {
+		log.Println("🧠 ปิด expansion cache")
+		return
+	}
+
+	if err := expansionCache.load(); err != nil && !os.IsNotExist(err) {
+		log.Printf("⚠️  โหลด expansion cache จาก %s ไม่ได้: %v", cfg.ExpansionCachePath, err)
+	}
+	stats, _ := expansionCache.snapshot()
+	log.Printf("🧠 expansion cache พร้อมใช้งาน (%d/%d รายการ, TTL %s)", stats.Entries, stats.Capacity, stats.TTL)
+
+	if cfg.ExpansionCachePath != "" {
+		go expansionCache.persist(time.Minute)
+	}
+}
+
+// initSynonyms โหลดพจนานุกรมคำพ้องและ stopword แล้วเฝ้าดูการแก้ไขไฟล์
+func initSynonyms() {
+	synonymDict = newSynonymDictionary(cfg.SynonymDir)
+	if _, err := synonymDict.reload(); err != nil {
+		log.Printf("⚠️  โหลดพจนานุกรมคำพ้องจาก %s ไม่สำเร็จ: %v", cfg.SynonymDir, err)
+	}
+
+	if cfg.IndexRefresh > 0 {
+		go synonymDict.watch(time.Duration(cfg.IndexRefresh) * time.Second)
+	}
+
+	stopwords = newStopwordList(cfg.StopwordFile)
+	if _, err := stopwords.reload(); err != nil {
+		log.Printf("⚠️  โหลด stopword จาก %s ไม่สำเร็จ: %v", cfg.StopwordFile, err)
+	}
+	if cfg.IndexRefresh > 0 {
+		go stopwords.watch(time.Duration(cfg.IndexRefresh) * time.Second)
+	}
+}
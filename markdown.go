@@ -0,0 +1,194 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// ชนิดของ block ใน markdown
+const (
+	blockHeading   = "heading"
+	blockParagraph = "paragraph"
+	blockList      = "list"
+	blockTable     = "table"
+	blockCode      = "code"
+)
+
+var (
+	headingPattern  = regexp.MustCompile(`^(#{1,6})\s+(.+?)\s*#*\s*$`)
+	listItemPattern = regexp.MustCompile(`^\s*([-*+]|\d+[.)])\s+`)
+	fencePattern    = regexp.MustCompile("^\\s*(```|~~~)")
+)
+
+// markdownBlock ส่วนของเอกสารที่ควรแสดงรวมกัน (บรรทัดเริ่มที่ 0, EndLine รวมบรรทัดสุดท้าย)
+type markdownBlock struct {
+	Kind        string
+	StartLine   int
+	EndLine     int
+	HeadingPath []string // หัวข้อที่ครอบ block นี้ (ของ heading block รวมตัวเองด้วย)
+}
+
+// parseMarkdownBlocks แบ่งเอกสารเป็น heading, paragraph, list, table, code
+// ย่อหน้าที่ลงท้ายด้วย ":" ตามด้วยรายการ จะรวมเป็น block เดียวกับรายการนั้น
+func parseMarkdownBlocks(lines []string) []markdownBlock {
+	var blocks []markdownBlock
+	var headings []string
+
+	add := func(kind string, start, end int) {
+		path := make([]string, len(headings))
+		copy(path, headings)
+		blocks = append(blocks, markdownBlock{Kind: kind, StartLine: start, EndLine: end, HeadingPath: path})
+	}
+
+	for i := 0; i < len(lines); {
+		line := lines[i]
+		trimmed := strings.TrimSpace(line)
+
+		switch {
+		case trimmed == "":
+			i++
+
+		case fencePattern.MatchString(line):
+			fence := fencePattern.FindStringSubmatch(line)[1]
+			end := i + 1
+			for end < len(lines) && !strings.HasPrefix(strings.TrimSpace(lines[end]), fence) {
+				end++
+			}
+			end = min(end, len(lines)-1)
+			add(blockCode, i, end)
+			i = end + 1
+
+		case headingPattern.MatchString(line):
+			m := headingPattern.FindStringSubmatch(line)
+			level := len(m[1])
+			if len(headings) >= level {
+				headings = headings[:level-1]
+			}
+			for len(headings) < level-1 {
+				headings = append(headings, "")
+			}
+			headings = append(headings, m[2])
+			add(blockHeading, i, i)
+			i++
+
+		case strings.HasPrefix(trimmed, "|"):
+			end := i
+			for end+1 < len(lines) && strings.HasPrefix(strings.TrimSpace(lines[end+1]), "|") {
+				end++
+			}
+			add(blockTable, i, end)
+			i = end + 1
+
+		case listItemPattern.MatchString(line):
+			end := listEnd(lines, i)
+			// รวมย่อหน้านำ เช่น "มีคุณสมบัติดังนี้:" เข้ากับรายการ
+			if n := len(blocks); n > 0 && blocks[n-1].Kind == blockParagraph &&
+				strings.HasSuffix(strings.TrimSpace(lines[blocks[n-1].EndLine]), ":") &&
+				onlyBlankBetween(lines, blocks[n-1].EndLine, i) {
+				blocks[n-1].Kind = blockList
+				blocks[n-1].EndLine = end
+			} else {
+				add(blockList, i, end)
+			}
+			i = end + 1
+
+		default:
+			end := i
+			for end+1 < len(lines) && isParagraphContinuation(lines[end+1]) {
+				end++
+			}
+			add(blockParagraph, i, end)
+			i = end + 1
+		}
+	}
+
+	return blocks
+}
+
+// listEnd หาบรรทัดสุดท้ายของรายการ (รายการต่อเนื่องหรือบรรทัดย่อหน้าเข้าไป)
+func listEnd(lines []string, start int) int {
+	end := start
+	for end+1 < len(lines) {
+		next := lines[end+1]
+		if strings.TrimSpace(next) == "" {
+			break
+		}
+		if !listItemPattern.MatchString(next) && !strings.HasPrefix(next, " ") && !strings.HasPrefix(next, "\t") {
+			break
+		}
+		end++
+	}
+	return end
+}
+
+func isParagraphContinuation(line string) bool {
+	trimmed := strings.TrimSpace(line)
+	return trimmed != "" &&
+		!headingPattern.MatchString(line) &&
+		!listItemPattern.MatchString(line) &&
+		!fencePattern.MatchString(line) &&
+		!strings.HasPrefix(trimmed, "|")
+}
+
+func onlyBlankBetween(lines []string, from, to int) bool {
+	for i := from + 1; i < to; i++ {
+		if strings.TrimSpace(lines[i]) != "" {
+			return false
+		}
+	}
+	return true
+}
+
+// lineBlockIndex สร้างตาราง บรรทัด → index ของ block (-1 = บรรทัดว่าง)
+func lineBlockIndex(lineCount int, blocks []markdownBlock) []int {
+	index := make([]int, lineCount)
+	for i := range index {
+		index[i] = -1
+	}
+	for b, block := range blocks {
+		for i := block.StartLine; i <= block.EndLine && i < lineCount; i++ {
+			index[i] = b
+		}
+	}
+	return index
+}
+
+// blockContextRange เลือกช่วงบรรทัดที่จะแสดงสำหรับบรรทัดที่เจอ:
+// ทั้ง block ที่ครอบอยู่ (heading จะแสดงพร้อม block ถัดไป) ถ้ายาวเกิน maxLines ใช้หน้าต่าง ±window ภายใน block
+func blockContextRange(blocks []markdownBlock, lineBlocks []int, line, maxLines, window int) (start, end int, headingPath []string) {
+	b := -1
+	if line < len(lineBlocks) {
+		b = lineBlocks[line]
+	}
+	if b < 0 {
+		return line, line, nil
+	}
+
+	block := blocks[b]
+	start, end = block.StartLine, block.EndLine
+	if block.Kind == blockHeading && b+1 < len(blocks) && blocks[b+1].Kind != blockHeading {
+		end = blocks[b+1].EndLine
+	}
+
+	if end-start+1 > maxLines {
+		start = max(start, line-window)
+		end = min(end, line+window)
+	}
+	return start, end, block.HeadingPath
+}
+
+// headingBreadcrumb heading path ที่ตัดระดับที่ข้ามไป (ว่าง) ออก
+func headingBreadcrumb(path []string) []string {
+	var parts []string
+	for _, heading := range path {
+		if heading != "" {
+			parts = append(parts, heading)
+		}
+	}
+	return parts
+}
+
+// formatHeadingPath แปลง heading path เป็นข้อความ เช่น "บทที่ 2 > 2.1 คุณสมบัติ"
+func formatHeadingPath(path []string) string {
+	return strings.Join(headingBreadcrumb(path), " > ")
+}
@@ -0,0 +1,110 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseMarkdownBlocks(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []markdownBlock
+	}{
+		{
+			name:  "heading และย่อหน้า",
+			lines: []string{"# ปูน", "ปูนซีเมนต์ถุงละ 150 บาท", "ส่งฟรีในเขตเมือง", "", "## ราคาส่ง", "สั่ง 100 ถุงขึ้นไป"},
+			want: []markdownBlock{
+				{Kind: blockHeading, StartLine: 0, EndLine: 0, HeadingPath: []string{"ปูน"}},
+				{Kind: blockParagraph, StartLine: 1, EndLine: 2, HeadingPath: []string{"ปูน"}},
+				{Kind: blockHeading, StartLine: 4, EndLine: 4, HeadingPath: []string{"ปูน", "ราคาส่ง"}},
+				{Kind: blockParagraph, StartLine: 5, EndLine: 5, HeadingPath: []string{"ปูน", "ราคาส่ง"}},
+			},
+		},
+		{
+			name:  "heading ข้ามระดับและกลับขึ้นระดับบน",
+			lines: []string{"# สินค้า", "### สี", "# บริการ"},
+			want: []markdownBlock{
+				{Kind: blockHeading, StartLine: 0, EndLine: 0, HeadingPath: []string{"สินค้า"}},
+				{Kind: blockHeading, StartLine: 1, EndLine: 1, HeadingPath: []string{"สินค้า", "", "สี"}},
+				{Kind: blockHeading, StartLine: 2, EndLine: 2, HeadingPath: []string{"บริการ"}},
+			},
+		},
+		{
+			name:  "ย่อหน้าที่ลงท้ายด้วย : รวมกับรายการ",
+			lines: []string{"มีคุณสมบัติดังนี้:", "- กันน้ำ", "- ทนแดด", "  ใช้ได้ 10 ปี", "", "ย่อหน้าใหม่"},
+			want: []markdownBlock{
+				{Kind: blockList, StartLine: 0, EndLine: 3, HeadingPath: []string{}},
+				{Kind: blockParagraph, StartLine: 5, EndLine: 5, HeadingPath: []string{}},
+			},
+		},
+		{
+			name:  "ตารางและ code block ที่มี # ข้างใน",
+			lines: []string{"| สินค้า | ราคา |", "|---|---|", "| ปูน | 150 |", "```", "# ไม่ใช่ heading", "```"},
+			want: []markdownBlock{
+				{Kind: blockTable, StartLine: 0, EndLine: 2, HeadingPath: []string{}},
+				{Kind: blockCode, StartLine: 3, EndLine: 5, HeadingPath: []string{}},
+			},
+		},
+		{
+			name:  "code block ที่ไม่ปิด",
+			lines: []string{"~~~", "a", "b"},
+			want: []markdownBlock{
+				{Kind: blockCode, StartLine: 0, EndLine: 2, HeadingPath: []string{}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseMarkdownBlocks(tt.lines); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseMarkdownBlocks() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBlockContextRange(t *testing.T) {
+	lines := []string{
+		"# ปูน",        // 0
+		"ปูนถุงละ 150", // 1
+		"ส่งฟรี",       // 2
+		"",             // 3
+		"- 1",          // 4
+		"- 2",          // 5
+		"- 3",          // 6
+		"- 4",          // 7
+		"- 5",          // 8
+		"- 6",          // 9
+		"",             // 10
+		"# ทราย",       // 11
+		"# หิน",        // 12
+	}
+	blocks := parseMarkdownBlocks(lines)
+	lineBlocks := lineBlockIndex(len(lines), blocks)
+
+	tests := []struct {
+		name               string
+		line, maxLines     int
+		wantStart, wantEnd int
+		wantHeading        []string
+	}{
+		{"บรรทัดในย่อหน้าได้ทั้งย่อหน้า", 2, 10, 1, 2, []string{"ปูน"}},
+		{"heading แสดงพร้อม block ถัดไป", 0, 10, 0, 2, []string{"ปูน"}},
+		{"heading ที่ตามด้วย heading แสดงตัวเดียว", 11, 10, 11, 11, []string{"ทราย"}},
+		{"block ยาวเกิน maxLines ใช้หน้าต่าง ±1", 7, 3, 6, 8, []string{"ปูน"}},
+		{"หน้าต่างไม่เกินขอบ block", 4, 3, 4, 5, []string{"ปูน"}},
+		{"บรรทัดว่าง", 3, 10, 3, 3, nil},
+		{"บรรทัดเกินเอกสาร", 20, 10, 20, 20, nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			start, end, heading := blockContextRange(blocks, lineBlocks, tt.line, tt.maxLines, 1)
+			if start != tt.wantStart || end != tt.wantEnd || !reflect.DeepEqual(heading, tt.wantHeading) {
+				t.Errorf("blockContextRange(line=%d, maxLines=%d) = %d-%d %q, want %d-%d %q",
+					tt.line, tt.maxLines, start, end, heading, tt.wantStart, tt.wantEnd, tt.wantHeading)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+package main
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// การ normalize ข้อความก่อนเทียบ ใช้เหมือนกันทั้งตอน index เอกสารและตอนค้นหา
+// ข้อความไทยที่ดูเหมือนกันอาจเก็บด้วยอักขระต่างกัน เช่น ำ กับ ํ+า, วรรณยุกต์พิมพ์ซ้ำ,
+// วรรณยุกต์พิมพ์ก่อนสระบน, ZWSP ที่คั่นคำ, เลขไทย, ตัวอักษร full-width
+// ลำดับขั้นตอน:
+//  1. NFC (เช่น e+◌́ → é) อักขระที่ประกอบรวมกันได้ชี้กลับไปทั้งช่วงเดิม
+//  2. ลบอักขระที่มองไม่เห็น (ZWSP, ZWNJ, ZWJ, BOM, soft hyphen)
+//  3. full-width → ASCII และเลขไทย → เลขอารบิก
+//  4. ตัวพิมพ์เล็ก
+//  5. ช่องว่างทุกชนิดที่ติดกันเหลือช่องว่างเดียว
+//  6. ํ+า → ำ (NFC ไม่รวมให้เพราะ ำ แยกได้เฉพาะแบบ compatibility)
+//  7. เรียงสระบน/ล่าง → วรรณยุกต์ → ์ และลบเครื่องหมายที่พิมพ์ซ้ำ
+
+// normalizedText ข้อความที่ normalize แล้ว พร้อมตำแหน่งในข้อความเดิมของทุก byte
+type normalizedText struct {
+	Text   string
+	starts []int // byte ที่ i มาจากข้อความเดิมช่วง [starts[i], ends[i])
+	ends   []int
+}
+
+// normUnit อักขระ 1 ตัวระหว่าง normalize และช่วง byte ในข้อความเดิม
+type normUnit struct {
+	r          rune
+	start, end int
+}
+
+// normalizeText normalize ข้อความสำหรับเทียบ (ไม่ต้องการตำแหน่งเดิม)
+func normalizeText(text string) string {
+	return normalizeWithOffsets(text).Text
+}
+
+// normalizeWithOffsets normalize ข้อความและเก็บตำแหน่งเดิม (ใช้แปลงตำแหน่งที่เจอกลับไปเป็นตำแหน่งในเอกสาร)
+func normalizeWithOffsets(text string) normalizedText {
+	units := make([]normUnit, 0, len(text))
+	forEachNFCRune(text, func(r rune, i, end int) {
+		if r = foldRune(r); r < 0 {
+			return
+		}
+
+		last := len(units) - 1
+		switch {
+		case r == ' ' && last >= 0 && units[last].r == ' ':
+			units[last].end = end
+		case r == 'า' && last >= 0 && units[last].r == 'ํ':
+			units[last] = normUnit{r: 'ำ', start: units[last].start, end: end}
+		case r == 'า' && last >= 1 && units[last-1].r == 'ํ' && isThaiToneMark(units[last].r):
+			// ํ+วรรณยุกต์+า → วรรณยุกต์+ำ (เช่น "น้ำ" ที่พิมพ์แยก)
+			units[last-1], units[last] = units[last], normUnit{r: 'ำ', start: units[last-1].start, end: end}
+		default:
+			units = append(units, normUnit{r: r, start: i, end: end})
+		}
+	})
+	units = reorderThaiMarks(units)
+
+	var builder strings.Builder
+	result := normalizedText{
+		starts: make([]int, 0, len(text)),
+		ends:   make([]int, 0, len(text)),
+	}
+	for _, u := range units {
+		n, _ := builder.WriteRune(u.r)
+		for k := 0; k < n; k++ {
+			result.starts = append(result.starts, u.start)
+			result.ends = append(result.ends, u.end)
+		}
+	}
+	result.Text = builder.String()
+	return result
+}
+
+// forEachNFCRune เรียก fn กับทุกอักขระของข้อความรูป NFC พร้อมช่วง byte [start, end) ในข้อความเดิม
+// ช่วงที่ NFC เปลี่ยน (ประกอบ/เรียงเครื่องหมายใหม่) ทุกอักขระชี้ไปทั้งช่วงนั้น ส่วนที่เหลือชี้ทีละตัว
+func forEachNFCRune(text string, fn func(r rune, start, end int)) {
+	each := func(segment string, offset int) {
+		for i := 0; i < len(segment); {
+			r, size := utf8.DecodeRuneInString(segment[i:])
+			fn(r, offset+i, offset+i+size)
+			i += size
+		}
+	}
+
+	if norm.NFC.IsNormalString(text) {
+		each(text, 0)
+		return
+	}
+	for i := 0; i < len(text); {
+		n := norm.NFC.NextBoundaryInString(text[i:], true)
+		if n <= 0 {
+			n = len(text) - i
+		}
+		segment := text[i : i+n]
+		if composed := norm.NFC.String(segment); composed != segment {
+			for _, r := range composed {
+				fn(r, i, i+n)
+			}
+		} else {
+			each(segment, i)
+		}
+		i += n
+	}
+}
+
+// foldRune แปลงอักขระ 1 ตัว (-1 = ลบทิ้ง)
+func foldRune(r rune) rune {
+	switch {
+	case r == '\u200b' || r == '\u200c' || r == '\u200d' || r == '\ufeff' || r == '\u00ad':
+		return -1
+	case r >= '！' && r <= '～': // full-width ASCII
+		r -= 0xFEE0
+	case r >= '๐' && r <= '๙':
+		return '0' + (r - '๐')
+	case unicode.IsSpace(r) || r == '\u3000':
+		return ' '
+	}
+	return unicode.ToLower(r)
+}
+
+// thaiMarkRank ลำดับของเครื่องหมายที่อยู่บน/ล่างพยัญชนะ (-1 = ไม่ใช่เครื่องหมาย)
+func thaiMarkRank(r rune) int {
+	switch {
+	case r == 'ั' || (r >= 'ิ' && r <= 'ฺ') || r == '็': // สระบน/ล่าง, ไม้ไต่คู้
+		return 0
+	case isThaiToneMark(r):
+		return 1
+	case r >= '์' && r <= '๎': // ์ ํ ๎
+		return 2
+	}
+	return -1
+}
+
+func isThaiToneMark(r rune) bool {
+	return r >= '่' && r <= '๋'
+}
+
+// reorderThaiMarks เรียงเครื่องหมายที่ติดกันหลังพยัญชนะตามลำดับมาตรฐาน และลบตัวที่ซ้ำ
+// เช่น ก+่+ิ → ก+ิ+่ และ ก+่+่ → ก+่
+func reorderThaiMarks(units []normUnit) []normUnit {
+	result := units[:0]
+	for i := 0; i < len(units); {
+		if thaiMarkRank(units[i].r) < 0 {
+			result = append(result, units[i])
+			i++
+			continue
+		}
+
+		j := i
+		for j < len(units) && thaiMarkRank(units[j].r) >= 0 {
+			j++
+		}
+		marks := append([]normUnit(nil), units[i:j]...)
+		// insertion sort แบบ stable (มีแค่ 2–3 ตัว)
+		for a := 1; a < len(marks); a++ {
+			for b := a; b > 0 && thaiMarkRank(marks[b].r) < thaiMarkRank(marks[b-1].r); b-- {
+				marks[b], marks[b-1] = marks[b-1], marks[b]
+			}
+		}
+		for k, mark := range marks {
+			if k > 0 && mark.r == marks[k-1].r {
+				last := &result[len(result)-1]
+				last.start, last.end = min(last.start, mark.start), max(last.end, mark.end)
+				continue
+			}
+			result = append(result, mark)
+		}
+		i = j
+	}
+	return result
+}
+
+// originalRange แปลงช่วง byte [start, end) ของข้อความที่ normalize แล้ว เป็นช่วงในข้อความเดิม
+func (n normalizedText) originalRange(start, end int) (int, int) {
+	from, to := n.starts[start], n.ends[start]
+	for i := start + 1; i < end; i++ {
+		from, to = min(from, n.starts[i]), max(to, n.ends[i])
+	}
+	return from, to
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNormalizeText(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want string
+	}{
+		{"ํ+า → ำ", "นํา", "นำ"},
+		{"ํ+วรรณยุกต์+า → วรรณยุกต์+ำ", "นํ้า", "น้ำ"},
+		{"วรรณยุกต์ก่อนสระบน", "ก่ิ", "กิ่"},
+		{"วรรณยุกต์ก่อนสระล่าง", "ปู้", "ปู้"},
+		{"การันต์ก่อนวรรณยุกต์", "ก์่", "ก่์"},
+		{"วรรณยุกต์ซ้ำ", "ก่่า", "ก่า"},
+		{"ZWSP และ BOM", "\ufeffปูน\u200bซีเมนต์", "ปูนซีเมนต์"},
+		{"full-width และตัวพิมพ์ใหญ่", "ＤＢ１２", "db12"},
+		{"เลขไทย", "๑,๐๐๐ ก้อน", "1,000 ก้อน"},
+		{"ช่องว่างหลายตัว", "ปูน \t\u00a0 ทราย", "ปูน ทราย"},
+		{"NFC", "cafe\u0301 E\u0301", "caf\u00e9 \u00e9"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := normalizeText(tt.text); got != tt.want {
+				t.Errorf("normalizeText(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizedOriginalRange(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		find string // คำที่หาในข้อความที่ normalize แล้ว
+		want string // ช่วงที่ต้องได้ในข้อความเดิม
+	}{
+		{"ำ ที่ประกอบจาก 2 ตัว", "x\u200bนําy", "นำ", "นํา"},
+		{"ำ ที่มีวรรณยุกต์คั่น", "ดื่มนํ้าเย็น", "น้ำ", "นํ้า"},
+		{"เรียงวรรณยุกต์ใหม่", "ราคาก่ิง", "กิ่ง", "ก่ิง"},
+		{"ลบวรรณยุกต์ซ้ำ", "ไม้้สัก", "ไม้", "ไม้้"},
+		{"full-width", "ราคา\u3000１００บาท", "100", "１００"},
+		{"เลขไทยหลัง ZWSP", "\u200b\u200bราคา ๕๐ บาท", "50", "๕๐"},
+		{"ช่องว่างที่ถูกรวม", "ปูน  \t ทราย", "ปูน ทราย", "ปูน  \t ทราย"},
+		{"NFC ประกอบอักขระ", "cafe\u0301 menu", "caf\u00e9", "cafe\u0301"},
+		{"NFC ชี้ทั้งช่วงที่ประกอบ", "re\u0301sume\u0301", "\u00e9", "e\u0301"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			normalized := normalizeWithOffsets(tt.text)
+			i := strings.Index(normalized.Text, tt.find)
+			if i < 0 {
+				t.Fatalf("ไม่พบ %q ใน %q", tt.find, normalized.Text)
+			}
+			start, end := normalized.originalRange(i, i+len(tt.find))
+			if got := tt.text[start:end]; got != tt.want {
+				t.Errorf("originalRange ของ %q = %q [%d:%d], want %q", tt.find, got, start, end, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeWithOffsetsLength(t *testing.T) {
+	// ทุก byte ของข้อความที่ normalize แล้วต้องมีตำแหน่งเดิม
+	for _, text := range []string{"", "abc", "นํ้า", "ＡＢ\u200b๑", "\xffก", "a\u0301\u0323b"} {
+		normalized := normalizeWithOffsets(text)
+		if len(normalized.starts) != len(normalized.Text) || len(normalized.ends) != len(normalized.Text) {
+			t.Errorf("normalizeWithOffsets(%q): offsets %d/%d, text %d bytes", text, len(normalized.starts), len(normalized.ends), len(normalized.Text))
+		}
+	}
+}
@@ -0,0 +1,101 @@
+package main
+
+import (
+	"log"
+	"math"
+	"sort"
+)
+
+// mergePassages รวม Match ของไฟล์เดียวกันที่ context ซ้อนกันหรือติดกันเป็น passage เดียว
+// passage เก็บทุกบรรทัดที่เจอ (HitLines), คำค้นหาและตำแหน่งที่เจอทั้งหมด และคะแนนสูงสุดของบรรทัดที่เจอ
+// (ไม่ใช้ผลรวม ไม่เช่นนั้น passage ยาวจะชนะเพียงเพราะยาว)
+// context ที่แค่ติดกัน (ไม่ซ้อน) จะรวมเมื่อยาวรวมไม่เกิน maxLines และทุก passage ยาวไม่เกิน maxPassageLines (0 = ไม่จำกัด)
+func mergePassages(matches []Match, maxLines, maxPassageLines int) []Match {
+	byFile := make(map[string][]Match)
+	var files []string
+	for _, match := range matches {
+		if _, exists := byFile[match.Filename]; !exists {
+			files = append(files, match.Filename)
+		}
+		byFile[match.Filename] = append(byFile[match.Filename], match)
+	}
+
+	var passages []Match
+	for _, file := range files {
+		list := byFile[file]
+		sort.SliceStable(list, func(i, j int) bool {
+			if list[i].StartLine != list[j].StartLine {
+				return list[i].StartLine < list[j].StartLine
+			}
+			return list[i].LineNum < list[j].LineNum
+		})
+
+		current := startPassage(list[0])
+		for _, next := range list[1:] {
+			length := max(next.EndLine, current.EndLine) - current.StartLine + 1
+			overlaps := next.StartLine <= current.EndLine
+			adjacent := next.StartLine == current.EndLine+1 && length <= maxLines
+			fits := maxPassageLines <= 0 || length <= maxPassageLines
+			if (overlaps || adjacent) && fits {
+				extendPassage(&current, next)
+				continue
+			}
+			passages = append(passages, current)
+			current = startPassage(next)
+		}
+		passages = append(passages, current)
+	}
+
+	if len(passages) < len(matches) {
+		log.Printf("🧩 รวม context ที่ซ้อนกัน: %d ผลลัพธ์ → %d passages", len(matches), len(passages))
+	}
+	return passages
+}
+
+// startPassage สร้าง passage จาก Match เดียว (คัดลอก slice เพื่อไม่ให้แก้ของเดิม)
+func startPassage(match Match) Match {
+	match.Context = append([]string(nil), match.Context...)
+	match.Keywords = append([]string(nil), match.Keywords...)
+	match.Hits = append([]Hit(nil), match.Hits...)
+	match.HitLines = []int{match.LineNum}
+	return match
+}
+
+// extendPassage ต่อ context ของ next เข้ากับ passage และรวมข้อมูลที่เจอ
+func extendPassage(passage *Match, next Match) {
+	if next.EndLine > passage.EndLine {
+		from := passage.EndLine + 1 - next.StartLine
+		passage.Context = append(passage.Context, next.Context[from:]...)
+		passage.EndLine = next.EndLine
+	}
+
+	if !containsInt(passage.HitLines, next.LineNum) {
+		passage.HitLines = append(passage.HitLines, next.LineNum)
+		passage.Score = math.Max(passage.Score, next.Score)
+		passage.Hits = append(passage.Hits, next.Hits...)
+	}
+	for _, keyword := range next.Keywords {
+		if !containsString(passage.Keywords, keyword) {
+			passage.Keywords = append(passage.Keywords, keyword)
+		}
+	}
+	sort.Ints(passage.HitLines)
+}
+
+func containsInt(list []int, value int) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestMergePassages(t *testing.T) {
+	match := func(file string, line, start, end int, score float64) Match {
+		var context []string
+		for i := start; i <= end; i++ {
+			context = append(context, fmt.Sprintf("line %d", i))
+		}
+		return Match{Filename: file, LineNum: line, StartLine: start, EndLine: end, Context: context, Score: score}
+	}
+
+	type passage struct {
+		file       string
+		start, end int
+		hitLines   []int
+		score      float64
+	}
+	tests := []struct {
+		name     string
+		matches  []Match
+		maxLines int
+		maxTotal int
+		want     []passage
+	}{
+		{
+			name:     "context ซ้อนกัน ใช้คะแนนสูงสุด",
+			matches:  []Match{match("a.md", 5, 3, 7, 1.5), match("a.md", 8, 6, 10, 2.0)},
+			maxLines: 30,
+			want:     []passage{{"a.md", 3, 10, []int{5, 8}, 2.0}},
+		},
+		{
+			name:     "ติดกันและรวมแล้วไม่เกิน maxLines",
+			matches:  []Match{match("a.md", 2, 1, 3, 1), match("a.md", 5, 4, 6, 1)},
+			maxLines: 6,
+			want:     []passage{{"a.md", 1, 6, []int{2, 5}, 1}},
+		},
+		{
+			name:     "ติดกันแต่รวมแล้วเกิน maxLines",
+			matches:  []Match{match("a.md", 2, 1, 3, 1), match("a.md", 5, 4, 6, 1)},
+			maxLines: 5,
+			want:     []passage{{"a.md", 1, 3, []int{2}, 1}, {"a.md", 4, 6, []int{5}, 1}},
+		},
+		{
+			name: "ผลที่ซ้อนกันต่อเนื่องหยุดรวมที่ maxPassageLines",
+			matches: []Match{
+				match("a.md", 2, 1, 4, 1), match("a.md", 5, 4, 7, 1),
+				match("a.md", 8, 7, 10, 1), match("a.md", 11, 10, 13, 1),
+			},
+			maxLines: 30,
+			maxTotal: 8,
+			want:     []passage{{"a.md", 1, 7, []int{2, 5}, 1}, {"a.md", 7, 13, []int{8, 11}, 1}},
+		},
+		{
+			name:     "คนละไฟล์ไม่รวม",
+			matches:  []Match{match("a.md", 5, 3, 7, 1), match("b.md", 5, 3, 7, 1)},
+			maxLines: 30,
+			want:     []passage{{"a.md", 3, 7, []int{5}, 1}, {"b.md", 3, 7, []int{5}, 1}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []passage
+			for _, p := range mergePassages(tt.matches, tt.maxLines, tt.maxTotal) {
+				if len(p.Context) != p.EndLine-p.StartLine+1 {
+					t.Errorf("passage %d–%d มี context %d บรรทัด", p.StartLine, p.EndLine, len(p.Context))
+				}
+				got = append(got, passage{p.Filename, p.StartLine, p.EndLine, p.HitLines, p.Score})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergePassages = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
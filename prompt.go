@@ -0,0 +1,113 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+)
+
+// defaultExpansionPrompt prompt เริ่มต้นที่ใช้เมื่อไม่มีไฟล์ใน PromptDir
+//
+//go:embed prompts/expansion.tmpl
+var defaultExpansionPrompt string
+
+// promptData ตัวแปรที่ใช้ใน template เช่น {{.Query}}, {{.ShopID}}
+type promptData struct {
+	Query  string
+	ShopID string
+}
+
+type cachedPrompt struct {
+	modTime time.Time
+	tmpl    *template.Template
+	version string // hash ของเนื้อหา template
+}
+
+var (
+	promptMu    sync.Mutex
+	promptCache = make(map[string]cachedPrompt)
+)
+
+// renderPrompt สร้าง prompt จาก template ของ shop (PromptDir/<shopid>/<name>) หรือของทุก shop (PromptDir/<name>)
+// แก้ไฟล์แล้วมีผลทันทีโดยไม่ต้อง restart ถ้าไม่มีไฟล์เลยใช้ fallback
+func renderPrompt(name, fallback string, data promptData) (string, error) {
+	tmpl, _, err := loadPromptTemplate(name, fallback, data.ShopID)
+	if err != nil {
+		return "", err
+	}
+
+	var builder strings.Builder
+	if err := tmpl.Execute(&builder, data); err != nil {
+		return "", fmt.Errorf("สร้าง prompt %s ไม่สำเร็จ: %w", name, err)
+	}
+	return builder.String(), nil
+}
+
+// loadPromptTemplate โหลด template ของ shop (ใช้ของที่ parse ไว้ถ้าไฟล์ไม่เปลี่ยน) คืน template และ hash ของเนื้อหา
+func loadPromptTemplate(name, fallback, shopID string) (*template.Template, string, error) {
+	var paths []string
+	if shopID != "" {
+		paths = append(paths, filepath.Join(cfg.PromptDir, shopID, name))
+	}
+	paths = append(paths, filepath.Join(cfg.PromptDir, name))
+
+	promptMu.Lock()
+	defer promptMu.Unlock()
+
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if cached, exists := promptCache[path]; exists && cached.modTime.Equal(info.ModTime()) {
+			return cached.tmpl, cached.version, nil
+		}
+
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return nil, "", err
+		}
+		tmpl, err := template.New(name).Parse(string(content))
+		if err != nil {
+			return nil, "", fmt.Errorf("template %s ไม่ถูกต้อง: %w", path, err)
+		}
+		log.Printf("📝 โหลด prompt template: %s", path)
+		cached := cachedPrompt{modTime: info.ModTime(), tmpl: tmpl, version: promptHash(string(content))}
+		promptCache[path] = cached
+		return tmpl, cached.version, nil
+	}
+
+	if cached, exists := promptCache["default:"+name]; exists {
+		return cached.tmpl, cached.version, nil
+	}
+	tmpl, err := template.New(name).Parse(fallback)
+	if err != nil {
+		return nil, "", err
+	}
+	cached := cachedPrompt{tmpl: tmpl, version: promptHash(fallback)}
+	promptCache["default:"+name] = cached
+	return tmpl, cached.version, nil
+}
+
+// promptVersion hash ของ template ที่ shop ใช้อยู่ ("" ถ้าโหลดไม่ได้)
+// ใช้เป็นส่วนหนึ่งของ key ใน cache เพื่อให้แก้ template แล้วไม่ได้ผลเก่าจาก cache
+func promptVersion(name, fallback, shopID string) string {
+	_, version, err := loadPromptTemplate(name, fallback, shopID)
+	if err != nil {
+		return ""
+	}
+	return version
+}
+
+func promptHash(content string) string {
+	h := fnv.New32a()
+	h.Write([]byte(content))
+	return fmt.Sprintf("%08x", h.Sum32())
+}
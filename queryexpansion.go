@@ -2,19 +2,86 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
+	"sort"
 	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+// หมวดของคำค้นหา (ใช้ถ่วงน้ำหนักคะแนน ดู keywordCategoryWeights)
+const (
+	keywordOriginal    = "original"
+	keywordDictionary  = "dictionary" // คำพ้องจากพจนานุกรม (ดู synonyms.go)
+	keywordCorrection  = "correction"
+	keywordCompound    = "compound"
+	keywordTranslation = "translation"
+	keywordSynonym     = "synonym"
+	keywordTranslit    = "transliteration" // คำทับศัพท์อีกอักษรที่พบในเอกสาร (ดู transliterate.go)
+	keywordFuzzy       = "fuzzy"           // คำในเอกสารที่ใกล้เคียงกับคำที่สะกดผิด (ดู fuzzy.go)
+	keywordSegment     = "segment"         // คำที่ได้จากการตัดคำ
+	keywordPhrase      = "phrase"          // วลี/คำที่ต้องมี ค้นหาตามที่เขียน (ดู querysyntax.go)
+	keywordWildcard    = "wildcard"        // คำที่มี * หรือ ?
+)
+
+// keywordCategoryWeights คำค้นหาเดิมมีน้ำหนักมากกว่าคำที่ LLM เดาให้
+var keywordCategoryWeights = map[string]float64{
+	keywordOriginal:    1.0,
+	keywordDictionary:  0.9,
+	keywordCorrection:  0.9,
+	keywordCompound:    0.8,
+	keywordTranslation: 0.7,
+	keywordSynonym:     0.6,
+	keywordTranslit:    0.3, // โครงเสียงชนกับคำทั่วไปได้ ต่ำกว่าคำแปลมาก
+	keywordFuzzy:       0.7,
+	keywordSegment:     0.5,
+	keywordPhrase:      1.0,
+	keywordWildcard:    0.8,
+}
+
+// maxExpansionTermRunes ความยาวสูงสุดของคำที่ LLM ส่งมา (ยาวกว่านี้ถือว่าเป็นประโยค ไม่ใช่คำค้นหา)
+const maxExpansionTermRunes = 60
+
+// searchKeyword คำค้นหา 1 คำพร้อมหมวดและน้ำหนัก
+type searchKeyword struct {
+	Text     string  `json:"text"`
+	Category string  `json:"category"`
+	Weight   float64 `json:"weight"`
+}
+
+// expansionResult ผลการขยายคำค้นหาจาก LLM (รูปแบบ JSON ที่บังคับด้วย expansionSchema)
+type expansionResult struct {
+	Translations []string `json:"translations"`
+	Synonyms     []string `json:"synonyms"`
+	Corrections  []string `json:"corrections"`
+	Compounds    []string `json:"compounds"`
+}
+
+// expansionSchema JSON schema ที่ส่งให้ Ollama ใน field "format"
+var expansionSchema = json.RawMessage(`{
+  "type": "object",
+  "properties": {
+    "translations": {"type": "array", "items": {"type": "string"}},
+    "synonyms": {"type": "array", "items": {"type": "string"}},
+    "corrections": {"type": "array", "items": {"type": "string"}},
+    "compounds": {"type": "array", "items": {"type": "string"}}
+  },
+  "required": ["translations", "synonyms", "corrections", "compounds"]
+}`)
+
 // OllamaQueryExpansionRequest for Ollama API
 type OllamaQueryExpansionRequest struct {
-	Model  string `json:"model"`
-	Prompt string `json:"prompt"`
-	Stream bool   `json:"stream"`
+	Model   string             `json:"model"`
+	Prompt  string             `json:"prompt"`
+	Stream  bool               `json:"stream"`
+	Format  json.RawMessage    `json:"format,omitempty"`
+	Options map[string]float64 `json:"options,omitempty"`
 }
 
 // OllamaQueryExpansionResponse from Ollama
@@ -22,119 +89,220 @@ type OllamaQueryExpansionResponse struct {
 	Response string `json:"response"`
 }
 
-// ExpandQueryWithOllama ใช้ Ollama LLM ขยายคำค้นหา + แปลภาษา
-func expandQueryWithOllama(cfg *Config, query string) []string {
-	prompt := fmt.Sprintf(`คุณเป็นผู้เชี่ยวชาญด้านการค้นหาข้อมูลภาษาไทยและอังกฤษ
-
-คำค้นหาของผู้ใช้: "%s"
-
-กรุณาสร้างรายการคำค้นหาที่เกี่ยวข้อง โดย:
-1. **แปลภาษา**: ถ้าเป็นไทยแปลเป็นอังกฤษ / ถ้าเป็นอังกฤษแปลเป็นไทย
-2. คำพ้องเสียงภาษาไทย (เช่น กระเบื้อง กะเบื้อง)
-3. แก้คำสะกดผิด (ถ้ามี)
-
-ตอบเฉพาะคำค้นหาที่เกี่ยวข้อง แยกด้วยช่องว่างเท่านั้น ไม่ต้องอธิบาย
+// expansionOptions ตัวเลือกการขยายคำค้นหาต่อ request
+type expansionOptions struct {
+	Enabled     bool // false = ไม่เรียก LLM ใช้เฉพาะการแบ่งคำ
+	MaxKeywords int  // จำนวนคำค้นหาสูงสุด (<= 0 ไม่จำกัด)
+	Fuzziness   fuzzinessLevel
+}
 
-**สำคัญ**: คำที่เป็นคำประสม ให้แยกออกเป็นคำเดี่ยวด้วย เพื่อให้หาเจอง่าย 
-ห้ามมีตัวอักษระพิเศษอื่นใด เช่น , . / \ ' " ( ) [ ] { } < > @ # $ % ^ & * - + = ~  ! ?
-ไม่ต้องบอกสิ่งที่ ai คิด ต้องการผลลัพธ์อย่างเดียว
-ให้มีทั้งคำติดกันและคำแยก เพื่อเพิ่มโอกาสหาเจอ ไม่ต้องมีเครื่องหมายพิเศษอื่นใด
+// ExpandQueryWithOllama ใช้ Ollama LLM ขยายคำค้นหา + แปลภาษา (prompt จาก prompts/expansion.tmpl ของ shop)
+// โดยบังคับให้ตอบเป็น JSON ตาม expansionSchema (ยกเลิกเมื่อ ctx ของ request ถูกยกเลิก เช่น client ตัดการเชื่อมต่อ)
+func expandQueryWithOllama(ctx context.Context, cfg *Config, shopID, query string) (expansionResult, error) {
+	var result expansionResult
 
-ถ้าไม่สามารถหาคำที่เกี่ยวข้องได้ ให้ตอบคำเดียวว่า: fail`, query)
+	prompt, err := renderPrompt("expansion.tmpl", defaultExpansionPrompt, promptData{Query: query, ShopID: shopID})
+	if err != nil {
+		return result, err
+	}
 
 	reqBody := OllamaQueryExpansionRequest{
-		Model:  "llama3.2", // ใช้ model เล็กๆ เพื่อความเร็ว
-		Prompt: prompt,
-		Stream: false,
+		Model:   cfg.ExpansionModel,
+		Prompt:  prompt,
+		Stream:  false,
+		Format:  expansionSchema,
+		Options: map[string]float64{"temperature": 0},
 	}
 
 	jsonData, err := json.Marshal(reqBody)
 	if err != nil {
-		log.Printf("❌ สร้าง JSON ไม่สำเร็จ: %v", err)
-		return []string{query}
+		return result, fmt.Errorf("สร้าง JSON ไม่สำเร็จ: %w", err)
 	}
 
-	resp, err := http.Post(cfg.OllamaHost+"/api/generate", "application/json", bytes.NewBuffer(jsonData))
+	ctx, cancel := context.WithTimeout(ctx, time.Duration(cfg.LLMTimeout)*time.Second)
+	defer cancel()
+
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", cfg.OllamaHost+"/api/generate", bytes.NewBuffer(jsonData))
 	if err != nil {
-		log.Printf("❌ เรียก Ollama ไม่สำเร็จ: %v", err)
-		return []string{"fail"}
+		return result, err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := apiHTTPClient.Do(httpReq)
+	if err != nil {
+		return result, fmt.Errorf("เรียก Ollama ไม่สำเร็จ: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
 		body, _ := io.ReadAll(resp.Body)
-		log.Printf("❌ Ollama API error: %s", string(body))
-		return []string{"fail"}
+		return result, fmt.Errorf("Ollama API error: %s", string(body))
 	}
 
 	var ollamaResp OllamaQueryExpansionResponse
 	if err := json.NewDecoder(resp.Body).Decode(&ollamaResp); err != nil {
-		log.Printf("❌ Decode response ไม่สำเร็จ: %v", err)
-		return []string{"fail"}
+		return result, fmt.Errorf("decode response ไม่สำเร็จ: %w", err)
 	}
 
-	// ถ้า Ollama ตอบว่า "fail" → ใช้คำค้นหาเดิม
-	response := strings.TrimSpace(ollamaResp.Response)
-	if strings.ToLower(response) == "fail" {
-		log.Printf("⚠️  Ollama ไม่สามารถขยายคำค้นหาได้ → ใช้คำเดิม")
-		return []string{query}
+	result, err = parseExpansionResult(ollamaResp.Response)
+	if err != nil {
+		return result, err
 	}
 
-	// แยกคำค้นหาจาก Ollama + ตัดคำภาษาไทยด้วย mapkha
-	result := extractSearchKeywords(response)
+	log.Printf("🔄 ขยายคำค้นหาได้: แปล %v, พ้อง %v, แก้คำผิด %v, คำประสม %v",
+		result.Translations, result.Synonyms, result.Corrections, result.Compounds)
+	return result, nil
+}
 
-	// เพิ่ม query เดิมถ้ายังไม่มี
-	originalLower := strings.ToLower(query)
-	hasOriginal := false
-	for _, r := range result {
-		if strings.ToLower(r) == originalLower {
-			hasOriginal = true
-			break
-		}
-	}
-	if !hasOriginal {
-		result = append([]string{query}, result...)
+// parseExpansionResult แปลงคำตอบของ LLM เป็น expansionResult แล้วตัดรายการที่ไม่ใช่คำค้นหาออก
+func parseExpansionResult(response string) (expansionResult, error) {
+	var result expansionResult
+	if err := json.Unmarshal([]byte(strings.TrimSpace(response)), &result); err != nil {
+		return result, fmt.Errorf("คำตอบของ LLM ไม่ใช่ JSON ตาม schema: %w", err)
 	}
 
-	// จำกัดไม่เกิน 15 คำค้นหา (เพิ่มจาก 10 เพราะตัดคำแล้ว)
-	if len(result) > 15 {
-		result = result[:15]
+	result.Translations = validExpansionTerms(result.Translations)
+	result.Synonyms = validExpansionTerms(result.Synonyms)
+	result.Corrections = validExpansionTerms(result.Corrections)
+	result.Compounds = validExpansionTerms(result.Compounds)
+	return result, nil
+}
+
+// validExpansionTerms เก็บเฉพาะคำที่ไม่ว่าง ไม่ยาวเกินไป และไม่มีบรรทัดใหม่หรืออักขระควบคุม
+func validExpansionTerms(terms []string) []string {
+	var valid []string
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if term == "" || utf8.RuneCountInString(term) > maxExpansionTermRunes {
+			continue
+		}
+		if strings.IndexFunc(term, unicode.IsControl) >= 0 {
+			log.Printf("   ⚠️  ข้ามคำที่ไม่ถูกต้องจาก LLM: %q", term)
+			continue
+		}
+		valid = append(valid, term)
 	}
+	return valid
+}
 
-	log.Printf("🔄 ขยายคำค้นหาได้ (พร้อม mapkha segmentation): %v", result)
+// SmartSearchKeywords รวมระบบขยายคำค้นหาอัจฉริยะ คืนคำค้นหาเรียงตามน้ำหนัก (คำค้นหาเดิมก่อน)
+func smartSearchKeywords(ctx context.Context, cfg *Config, shopID, query string, opts expansionOptions) []searchKeyword {
+	builder := newKeywordSet()
+	builder.add(query, keywordOriginal)
 
-	return result
-}
+	// คำพ้องจากพจนานุกรม ใช้ก่อน LLM เสมอ (ผลคงที่และใช้ได้แม้ Ollama ล่ม)
+	dictionaryTerms := synonymDict.expand(shopID, query)
+	builder.addAll(dictionaryTerms, keywordDictionary)
 
-// SmartSearchKeywords รวมระบบขยายคำค้นหาอัจฉริยะ
-func smartSearchKeywords(cfg *Config, query string) []string {
-	// 1. ขยายคำค้นหาด้วย Ollama (แปลภาษา + คำพ้องเสียง + คำที่เกี่ยวข้อง)
-	expandedQueries := expandQueryWithOllama(cfg, query)
+	// คำทับศัพท์ไทย↔อังกฤษที่มีในเอกสาร (ไม่ต้องพึ่ง LLM)
+	builder.addAll(docIndex.transliterations(shopID, query), keywordTranslit)
 
-	// ถ้า Ollama fail → ใช้คำเดิม + แบ่งคำไทย
-	if len(expandedQueries) == 1 && expandedQueries[0] == "fail" {
-		log.Printf("⚠️  Ollama fail → ใช้คำค้นหาเดิม + แบ่งคำไทย")
-		simpleWords := extractKeywords(query)
-		return simpleWords
+	// คำที่สะกดผิดเล็กน้อย → คำที่ใกล้เคียงที่สุดในเอกสาร (ไม่ต้องพึ่ง LLM)
+	if fuzzyTerms := docIndex.fuzzyTerms(shopID, query, opts.Fuzziness); len(fuzzyTerms) > 0 {
+		log.Printf("🔤 คำใกล้เคียง (fuzzy): %v", fuzzyTerms)
+		builder.addAll(fuzzyTerms, keywordFuzzy)
 	}
 
-	// 2. เพิ่มการแบ่งคำภาษาไทยแบบง่าย
-	simpleWords := extractKeywords(query)
+	useLLM := opts.Enabled
+	if useLLM && cfg.SynonymSkipLLM && len(dictionaryTerms) > 0 {
+		log.Printf("📖 พจนานุกรมมีคำพ้องแล้ว → ไม่เรียก LLM")
+		useLLM = false
+	}
 
-	// รวมทุกคำค้นหา
-	allKeywords := append(expandedQueries, simpleWords...)
+	if useLLM {
+		// ขยายคำค้นหาด้วย Ollama (แปลภาษา + คำพ้อง + แก้คำผิด + คำประสม) ใช้ผลจาก cache ถ้ามี
+		// prompt ต่างกันได้ในแต่ละ shop และแก้ได้ตลอด จึงแยก cache ตาม shop และเนื้อหา template
+		cacheModel := cfg.ExpansionModel + "@" + shopID + "#" + promptVersion("expansion.tmpl", defaultExpansionPrompt, shopID)
+		expansion, cached := expansionCache.get(query, cacheModel)
+		if cached {
+			log.Printf("⚡ ใช้ผลขยายคำค้นหาจาก cache")
+		} else {
+			var err error
+			expansion, err = expandQueryWithOllama(ctx, cfg, shopID, query)
+			if err != nil {
+				// ไม่ cache กรณีล้มเหลว เพื่อให้ลองใหม่ครั้งหน้า
+				log.Printf("⚠️  ขยายคำค้นหาไม่สำเร็จ → ใช้คำค้นหาเดิม + แบ่งคำไทย: %v", err)
+			} else {
+				expansionCache.put(query, cacheModel, expansion)
+			}
+		}
 
-	// ลบซ้ำ
-	uniqueKeywords := make(map[string]bool)
-	var final []string
+		builder.addAll(expansion.Corrections, keywordCorrection)
+		for _, correction := range expansion.Corrections {
+			builder.addAll(synonymDict.expand(shopID, correction), keywordDictionary)
+		}
+		builder.addAll(expansion.Compounds, keywordCompound)
+		builder.addAll(expansion.Translations, keywordTranslation)
+		builder.addAll(expansion.Synonyms, keywordSynonym)
+	} else if !opts.Enabled {
+		log.Printf("⏭️  ไม่ขยายคำค้นหา → ใช้คำค้นหาเดิม + พจนานุกรม + แบ่งคำไทย")
+	}
 
-	for _, kw := range allKeywords {
-		lower := strings.ToLower(kw)
-		if !uniqueKeywords[lower] && len(kw) >= 2 {
-			uniqueKeywords[lower] = true
-			final = append(final, kw)
+	// ตัดคำภาษาไทยของทุกคำ (คำประสมจะหาเจอแม้เขียนแยกกันในเอกสาร)
+	// ยกเว้นคำทับศัพท์ ซึ่ง mapkha มักตัดเป็นเศษคำที่ไม่มีความหมาย เช่น "เคเ|บิ|้ล"
+	for _, kw := range builder.keywords {
+		if hasThaiCharacters(kw.Text) && kw.Category != keywordTranslit {
+			builder.addAll(segmentThaiText(shopID, kw.Text), keywordSegment)
 		}
 	}
+	builder.addAll(extractKeywords(query), keywordSegment)
+
+	return limitKeywords(builder.keywords, opts.MaxKeywords)
+}
+
+// keywordSet รวมคำค้นหาโดยไม่ซ้ำ (ไม่สนตัวพิมพ์) คำที่เพิ่มก่อนได้หมวดนั้น
+type keywordSet struct {
+	seen     map[string]bool
+	keywords []searchKeyword
+}
+
+func newKeywordSet() *keywordSet {
+	return &keywordSet{seen: make(map[string]bool)}
+}
+
+func (s *keywordSet) add(text, category string) {
+	text = strings.TrimSpace(text)
+	key := normalizeText(text)
+	if len(text) < 2 || s.seen[key] {
+		return
+	}
+	// คำที่ได้จากการตัดคำต้องยาวพอและไม่ใช่ stopword (คำจากพจนานุกรม/LLM ใช้ตามที่ได้)
+	if category == keywordSegment && !isSearchableKeyword(text) {
+		log.Printf("   🚫 ข้าม stopword/คำสั้น %q (%s)", text, category)
+		return
+	}
+	s.seen[key] = true
+	s.keywords = append(s.keywords, searchKeyword{Text: text, Category: category, Weight: keywordCategoryWeights[category]})
+}
+
+func (s *keywordSet) addAll(texts []string, category string) {
+	for _, text := range texts {
+		s.add(text, category)
+	}
+}
 
-	return final
+// limitKeywords เรียงคำค้นหาตามน้ำหนัก (คำที่เท่ากันคงลำดับเดิม คำค้นหาเดิมจึงอยู่หน้าสุด) แล้วจำกัดจำนวน
+// keywordSet เก็บตามลำดับที่เพิ่ม ซึ่งไม่ใช่ลำดับน้ำหนัก (เช่น คำจากพจนานุกรมเพิ่มก่อนคำแก้คำผิดจาก LLM)
+func limitKeywords(keywords []searchKeyword, maxKeywords int) []searchKeyword {
+	sort.SliceStable(keywords, func(i, j int) bool { return keywords[i].Weight > keywords[j].Weight })
+	if maxKeywords > 0 && len(keywords) > maxKeywords {
+		log.Printf("✂️  จำกัดคำค้นหา %d → %d คำ", len(keywords), maxKeywords)
+		return keywords[:maxKeywords]
+	}
+	return keywords
+}
+
+// expansionOptions ค่าจาก request (ไม่ระบุ = ค่าจาก Config)
+func (req SearchRequestSimple) expansionOptions() expansionOptions {
+	opts := expansionOptions{Enabled: cfg.ExpansionEnabled, MaxKeywords: cfg.ExpansionMaxKeywords, Fuzziness: cfg.Fuzziness}
+	if req.Expand != nil {
+		opts.Enabled = *req.Expand
+	}
+	if req.MaxKeywords > 0 {
+		opts.MaxKeywords = req.MaxKeywords
+	}
+	if req.Fuzziness != nil {
+		opts.Fuzziness = *req.Fuzziness
+	}
+	return opts
 }
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestLimitKeywords(t *testing.T) {
+	kw := func(text, category string) searchKeyword {
+		return searchKeyword{Text: text, Category: category, Weight: keywordCategoryWeights[category]}
+	}
+	texts := func(keywords []searchKeyword) []string {
+		var result []string
+		for _, k := range keywords {
+			result = append(result, k.Text)
+		}
+		return result
+	}
+
+	tests := []struct {
+		name     string
+		keywords []searchKeyword
+		max      int
+		want     []string
+	}{
+		{
+			name: "คำแก้คำผิดที่เพิ่มทีหลังไม่ถูกตัด",
+			keywords: []searchKeyword{
+				kw("กระเบือง", keywordOriginal),
+				kw("โจตัน", keywordTranslit),
+				kw("กะเบื้อง", keywordFuzzy),
+				kw("กระเบื้อง", keywordCorrection),
+				kw("กระ", keywordSegment),
+			},
+			max:  2,
+			want: []string{"กระเบือง", "กระเบื้อง"},
+		},
+		{
+			name: "น้ำหนักเท่ากันคงลำดับเดิม",
+			keywords: []searchKeyword{
+				kw("ปูน", keywordOriginal),
+				kw("cement", keywordTranslation),
+				kw("ซีเมนต์", keywordFuzzy),
+				kw("ปูนซีเมนต์", keywordCompound),
+			},
+			max:  0,
+			want: []string{"ปูน", "ปูนซีเมนต์", "cement", "ซีเมนต์"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := texts(limitKeywords(tt.keywords, tt.max)); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("limitKeywords = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
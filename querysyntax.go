@@ -0,0 +1,351 @@
+package main
+
+import (
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+// ไวยากรณ์คำค้นหา แปลงก่อนขยายคำค้นหา:
+//
+//	คำธรรมดา               คำค้นหาอิสระ รวมกันแล้วส่งไปขยายคำ (พจนานุกรม, ทับศัพท์, fuzzy, LLM, ตัดคำ)
+//	"เหล็กเส้น DB12"         วลี ค้นหาตามที่เขียน ไม่ขยายคำ
+//	+คำ หรือ คำ AND คำ       ต้องมีใน passage
+//	-คำ หรือ NOT คำ          ต้องไม่มีใน passage
+//	OR                      ค่าเริ่มต้น (มีคำใดก็ได้)
+//	กระเบื้อง*, DB?2          wildcard: * = อักขระที่ไม่ใช่ช่องว่างกี่ตัวก็ได้, ? = 1 ตัว (? ท้ายคำคือเครื่องหมายคำถาม)
+//	file:promotion.md       เฉพาะไฟล์ (ใช้ * ได้, -file: = ไม่เอาไฟล์นี้)
+//	heading:"บทที่ 2"        เฉพาะ passage ที่หัวข้อมีข้อความนี้
+//
+// คำค้นหาต้องมีคำที่ต้องการอย่างน้อย 1 คำ: filter และ -คำ อย่างเดียว (เช่น "file:promotion.md") ถูกปฏิเสธ
+// ไม่รองรับวงเล็บ AND มีผลกับคำทั้งสองข้าง (แบบ Lucene) และ AND/OR/NOT ต้องเป็นตัวพิมพ์ใหญ่
+// คำที่มี + - AND NOT ค้นหาตามที่เขียนเหมือนวลี ส่วนคำค้นหาที่ไม่มีไวยากรณ์เลยทำงานเหมือนเดิม
+
+// queryOccur เงื่อนไขของคำค้นหา 1 ส่วน
+type queryOccur int
+
+const (
+	occurShould  queryOccur = iota // มีหรือไม่มีก็ได้ (ใช้ให้คะแนน)
+	occurMust                      // ต้องมี
+	occurMustNot                   // ต้องไม่มี
+)
+
+// ชนิดของคำค้นหา 1 ส่วน
+const (
+	clauseTerm     = "term"
+	clausePhrase   = "phrase"
+	clauseWildcard = "wildcard"
+)
+
+// field ที่ใช้กรองผลลัพธ์
+const (
+	filterFile    = "file"
+	filterHeading = "heading"
+)
+
+type queryClause struct {
+	Text  string
+	Kind  string
+	Occur queryOccur
+}
+
+type queryFilter struct {
+	Field  string
+	Value  string
+	Negate bool
+}
+
+// parsedQuery คำค้นหาที่แยกส่วนแล้ว
+type parsedQuery struct {
+	Clauses []queryClause
+	Filters []queryFilter
+}
+
+// queryToken คำ 1 คำจากการแยกด้วยช่องว่าง (ข้อความในเครื่องหมายคำพูดนับเป็นคำเดียว)
+type queryToken struct {
+	text   string
+	quoted bool // ขึ้นต้นด้วยเครื่องหมายคำพูด (วลี)
+	prefix rune // '+', '-' หรือ 0
+}
+
+// parseQuery แยกคำค้นหาตามไวยากรณ์ด้านบน
+func parseQuery(query string) parsedQuery {
+	var q parsedQuery
+	pendingAnd, pendingNot := false, false
+	for _, tok := range lexQuery(query) {
+		if !tok.quoted && tok.prefix == 0 {
+			switch tok.text {
+			case "AND":
+				// คำก่อนหน้าต้องมีด้วย
+				if n := len(q.Clauses); n > 0 && q.Clauses[n-1].Occur == occurShould {
+					q.Clauses[n-1].Occur = occurMust
+				}
+				pendingAnd = true
+				continue
+			case "OR":
+				continue
+			case "NOT":
+				pendingNot = true
+				continue
+			}
+		}
+
+		negate := tok.prefix == '-' || pendingNot
+		occur := occurShould
+		switch {
+		case negate:
+			occur = occurMustNot
+		case tok.prefix == '+' || pendingAnd:
+			occur = occurMust
+		}
+		pendingAnd, pendingNot = false, false
+
+		if !tok.quoted {
+			if field, value, ok := parseQueryFilter(tok.text); ok {
+				if value != "" {
+					q.Filters = append(q.Filters, queryFilter{Field: field, Value: value, Negate: negate})
+				}
+				continue
+			}
+		}
+
+		kind := clauseTerm
+		switch {
+		case tok.quoted:
+			kind = clausePhrase
+		case isWildcardPattern(tok.text):
+			kind = clauseWildcard
+			if strings.Trim(tok.text, "*?") == "" {
+				continue // "*" อย่างเดียวตรงกับทุกบรรทัด
+			}
+		}
+		if strings.TrimSpace(tok.text) != "" {
+			q.Clauses = append(q.Clauses, queryClause{Text: tok.text, Kind: kind, Occur: occur})
+		}
+	}
+	return q
+}
+
+// lexQuery แยกคำค้นหาด้วยช่องว่าง โดยข้อความในเครื่องหมายคำพูดเป็นคำเดียว (รวมถึง heading:"บทที่ 2")
+func lexQuery(query string) []queryToken {
+	var tokens []queryToken
+	runes := []rune(query)
+	for i := 0; i < len(runes); {
+		if unicode.IsSpace(runes[i]) {
+			i++
+			continue
+		}
+
+		var tok queryToken
+		if (runes[i] == '+' || runes[i] == '-') && i+1 < len(runes) && !unicode.IsSpace(runes[i+1]) {
+			tok.prefix = runes[i]
+			i++
+		}
+		tok.quoted = isQuoteRune(runes[i])
+
+		var builder strings.Builder
+		inQuote := false
+		for ; i < len(runes) && (inQuote || !unicode.IsSpace(runes[i])); i++ {
+			if isQuoteRune(runes[i]) {
+				inQuote = !inQuote
+				continue
+			}
+			builder.WriteRune(runes[i])
+		}
+		tok.text = strings.TrimSpace(builder.String())
+		if tok.text != "" {
+			tokens = append(tokens, tok)
+		}
+	}
+	return tokens
+}
+
+// isQuoteRune เครื่องหมายคำพูดตรงและแบบโค้งที่พิมพ์จากมือถือ
+func isQuoteRune(r rune) bool {
+	return r == '"' || r == '“' || r == '”'
+}
+
+// parseQueryFilter แยก "file:x" / "heading:x" (field อื่นถือเป็นคำค้นหาธรรมดา เช่น "10:30")
+func parseQueryFilter(text string) (field, value string, ok bool) {
+	i := strings.IndexRune(text, ':')
+	if i <= 0 {
+		return "", "", false
+	}
+	field = strings.ToLower(text[:i])
+	if field != filterFile && field != filterHeading {
+		return "", "", false
+	}
+	return field, strings.TrimSpace(text[i+1:]), true
+}
+
+// freeText คำอิสระ (ไม่มีเครื่องหมายคำพูด/ตัวดำเนินการ/wildcard) ที่จะส่งไปขยายคำค้นหา
+func (q parsedQuery) freeText() string {
+	var terms []string
+	for _, c := range q.Clauses {
+		if c.Kind == clauseTerm && c.Occur == occurShould {
+			terms = append(terms, c.Text)
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// literalKeywords คำที่ค้นหาตามที่เขียน (วลี, wildcard, คำที่ต้องมี) ไม่ผ่านการขยายคำ
+func (q parsedQuery) literalKeywords() []searchKeyword {
+	var keywords []searchKeyword
+	for _, c := range q.Clauses {
+		if c.Occur == occurMustNot || (c.Kind == clauseTerm && c.Occur == occurShould) {
+			continue
+		}
+		category := keywordPhrase
+		if c.Kind == clauseWildcard {
+			category = keywordWildcard
+		}
+		keywords = append(keywords, searchKeyword{Text: c.Text, Category: category, Weight: keywordCategoryWeights[category]})
+	}
+	return keywords
+}
+
+// plainText ข้อความของคำที่ต้องการทั้งหมดโดยไม่มีไวยากรณ์ (ใช้สร้าง embedding สำหรับ vector search)
+func (q parsedQuery) plainText() string {
+	var terms []string
+	for _, c := range q.Clauses {
+		if c.Occur != occurMustNot {
+			terms = append(terms, strings.Trim(c.Text, "*?"))
+		}
+	}
+	return strings.Join(terms, " ")
+}
+
+// highlightTerms คำที่ highlight เสมอ: คำอิสระทั้งข้อความ (เหมือนคำค้นหาเดิม) และทุกคำที่ต้องการ
+func (q parsedQuery) highlightTerms() []string {
+	var terms []string
+	if free := q.freeText(); free != "" {
+		terms = append(terms, free)
+	}
+	for _, kw := range q.literalKeywords() {
+		terms = append(terms, kw.Text)
+	}
+	return terms
+}
+
+// isEmpty ไม่มีคำที่ต้องการเลย (มีแต่ -คำ หรือ filter)
+func (q parsedQuery) isEmpty() bool {
+	for _, c := range q.Clauses {
+		if c.Occur != occurMustNot {
+			return false
+		}
+	}
+	return true
+}
+
+// hasConstraints มีเงื่อนไขที่ต้องตรวจกับผลลัพธ์ (คำที่ต้องมี/ต้องไม่มี หรือ filter)
+func (q parsedQuery) hasConstraints() bool {
+	if len(q.Filters) > 0 {
+		return true
+	}
+	for _, c := range q.Clauses {
+		if c.Occur != occurShould {
+			return true
+		}
+	}
+	return false
+}
+
+// accept ผลลัพธ์ผ่านทุกเงื่อนไขหรือไม่ (ตรวจทั้ง passage ไม่ใช่เฉพาะบรรทัดที่เจอ)
+func (q parsedQuery) accept(match Match) bool {
+	for _, f := range q.Filters {
+		if f.matches(match) == f.Negate {
+			return false
+		}
+	}
+
+	text := strings.Join(match.Context, "\n")
+	for _, c := range q.Clauses {
+		found := len(findKeywordHits(text, c.Text)) > 0
+		if (c.Occur == occurMust && !found) || (c.Occur == occurMustNot && found) {
+			return false
+		}
+	}
+	return true
+}
+
+// filterMatches เก็บเฉพาะผลลัพธ์ที่ผ่านเงื่อนไข
+func (q parsedQuery) filterMatches(matches []Match) []Match {
+	if !q.hasConstraints() {
+		return matches
+	}
+	kept := matches[:0]
+	for _, match := range matches {
+		if q.accept(match) {
+			kept = append(kept, match)
+		}
+	}
+	return kept
+}
+
+// matches file: เทียบกับชื่อไฟล์หรือ path ใน shop (ใช้ * ได้), heading: เป็น substring ของหัวข้อใดก็ได้
+func (f queryFilter) matches(match Match) bool {
+	switch f.Field {
+	case filterFile:
+		pattern := strings.ToLower(f.Value)
+		rel := strings.ToLower(relativeDocPath(match.Filename))
+		base := strings.ToLower(path.Base(rel))
+		for _, name := range []string{base, rel} {
+			if ok, _ := path.Match(pattern, name); ok {
+				return true
+			}
+		}
+		return false
+	case filterHeading:
+		value := normalizeText(f.Value)
+		for _, heading := range match.HeadingPath {
+			if strings.Contains(normalizeText(heading), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// isWildcardPattern มี * หรือ ? ที่ไม่ได้อยู่ท้ายคำ ("ราคาเท่าไหร่?" ไม่ใช่ wildcard)
+func isWildcardPattern(text string) bool {
+	return strings.ContainsRune(text, '*') || strings.ContainsRune(strings.TrimRight(text, "?"), '?')
+}
+
+// wildcardCache regexp ของ wildcard ที่ compile แล้ว (findKeywordHits เรียกทุกบรรทัด)
+var wildcardCache = struct {
+	sync.Mutex
+	patterns map[string]*regexp.Regexp
+}{patterns: make(map[string]*regexp.Regexp)}
+
+// maxWildcardCache จำนวน pattern สูงสุดใน cache (เกินแล้วล้างทั้งหมด คำค้นหาแต่ละครั้งมี pattern ไม่กี่ตัว)
+const maxWildcardCache = 256
+
+// wildcardRegexp แปลง pattern ที่ normalize แล้วเป็น regexp (* = \S*, ? = \S)
+func wildcardRegexp(pattern string) *regexp.Regexp {
+	wildcardCache.Lock()
+	defer wildcardCache.Unlock()
+	if re, ok := wildcardCache.patterns[pattern]; ok {
+		return re
+	}
+	if len(wildcardCache.patterns) >= maxWildcardCache {
+		wildcardCache.patterns = make(map[string]*regexp.Regexp)
+	}
+
+	var builder strings.Builder
+	for _, r := range pattern {
+		switch r {
+		case '*':
+			builder.WriteString(`\S*`)
+		case '?':
+			builder.WriteString(`\S`)
+		default:
+			builder.WriteString(regexp.QuoteMeta(string(r)))
+		}
+	}
+	re := regexp.MustCompile(builder.String())
+	wildcardCache.patterns[pattern] = re
+	return re
+}
@@ -0,0 +1,213 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  parsedQuery
+	}{
+		{
+			name:  "คำธรรมดา",
+			query: "ปูน ทราย",
+			want: parsedQuery{Clauses: []queryClause{
+				{Text: "ปูน", Kind: clauseTerm, Occur: occurShould},
+				{Text: "ทราย", Kind: clauseTerm, Occur: occurShould},
+			}},
+		},
+		{
+			name:  "วลี",
+			query: `"เหล็กเส้น DB12" ราคา`,
+			want: parsedQuery{Clauses: []queryClause{
+				{Text: "เหล็กเส้น DB12", Kind: clausePhrase, Occur: occurShould},
+				{Text: "ราคา", Kind: clauseTerm, Occur: occurShould},
+			}},
+		},
+		{
+			name:  "เครื่องหมายคำพูดไม่ปิด",
+			query: `"เหล็กเส้น DB12`,
+			want: parsedQuery{Clauses: []queryClause{
+				{Text: "เหล็กเส้น DB12", Kind: clausePhrase, Occur: occurShould},
+			}},
+		},
+		{
+			name:  "เครื่องหมายคำพูดแบบโค้ง",
+			query: "“ปูน ซีเมนต์”",
+			want: parsedQuery{Clauses: []queryClause{
+				{Text: "ปูน ซีเมนต์", Kind: clausePhrase, Occur: occurShould},
+			}},
+		},
+		{
+			name:  "+ และ -",
+			query: "+ปูน -ทราย",
+			want: parsedQuery{Clauses: []queryClause{
+				{Text: "ปูน", Kind: clauseTerm, Occur: occurMust},
+				{Text: "ทราย", Kind: clauseTerm, Occur: occurMustNot},
+			}},
+		},
+		{
+			name:  "- ที่มีช่องว่างตามหลังไม่ใช่ตัวดำเนินการ",
+			query: "ปูน - ทราย",
+			want: parsedQuery{Clauses: []queryClause{
+				{Text: "ปูน", Kind: clauseTerm, Occur: occurShould},
+				{Text: "-", Kind: clauseTerm, Occur: occurShould},
+				{Text: "ทราย", Kind: clauseTerm, Occur: occurShould},
+			}},
+		},
+		{
+			name:  "-วลี",
+			query: `-"ปูน ซีเมนต์"`,
+			want: parsedQuery{Clauses: []queryClause{
+				{Text: "ปูน ซีเมนต์", Kind: clausePhrase, Occur: occurMustNot},
+			}},
+		},
+		{
+			name:  "a AND b OR c",
+			query: "a AND b OR c",
+			want: parsedQuery{Clauses: []queryClause{
+				{Text: "a", Kind: clauseTerm, Occur: occurMust},
+				{Text: "b", Kind: clauseTerm, Occur: occurMust},
+				{Text: "c", Kind: clauseTerm, Occur: occurShould},
+			}},
+		},
+		{
+			name:  "and ตัวพิมพ์เล็กเป็นคำธรรมดา",
+			query: "a and b",
+			want: parsedQuery{Clauses: []queryClause{
+				{Text: "a", Kind: clauseTerm, Occur: occurShould},
+				{Text: "and", Kind: clauseTerm, Occur: occurShould},
+				{Text: "b", Kind: clauseTerm, Occur: occurShould},
+			}},
+		},
+		{
+			name:  "NOT คำ",
+			query: "ปูน NOT ทราย",
+			want: parsedQuery{Clauses: []queryClause{
+				{Text: "ปูน", Kind: clauseTerm, Occur: occurShould},
+				{Text: "ทราย", Kind: clauseTerm, Occur: occurMustNot},
+			}},
+		},
+		{
+			name:  "file:",
+			query: "ปูน file:promotion.md",
+			want: parsedQuery{
+				Clauses: []queryClause{{Text: "ปูน", Kind: clauseTerm, Occur: occurShould}},
+				Filters: []queryFilter{{Field: filterFile, Value: "promotion.md"}},
+			},
+		},
+		{
+			name:  "NOT file:",
+			query: "ปูน NOT file:promotion.md",
+			want: parsedQuery{
+				Clauses: []queryClause{{Text: "ปูน", Kind: clauseTerm, Occur: occurShould}},
+				Filters: []queryFilter{{Field: filterFile, Value: "promotion.md", Negate: true}},
+			},
+		},
+		{
+			name:  "-file: และ heading: ในเครื่องหมายคำพูด",
+			query: `ลา -file:*.txt heading:"บทที่ 2"`,
+			want: parsedQuery{
+				Clauses: []queryClause{{Text: "ลา", Kind: clauseTerm, Occur: occurShould}},
+				Filters: []queryFilter{
+					{Field: filterFile, Value: "*.txt", Negate: true},
+					{Field: filterHeading, Value: "บทที่ 2"},
+				},
+			},
+		},
+		{
+			name:  "field อื่นเป็นคำธรรมดา",
+			query: "10:30",
+			want: parsedQuery{Clauses: []queryClause{
+				{Text: "10:30", Kind: clauseTerm, Occur: occurShould},
+			}},
+		},
+		{
+			name:  "file: ที่ไม่มีค่าถูกข้าม",
+			query: "ปูน file:",
+			want: parsedQuery{Clauses: []queryClause{
+				{Text: "ปูน", Kind: clauseTerm, Occur: occurShould},
+			}},
+		},
+		{
+			name:  "? ท้ายคำไม่ใช่ wildcard",
+			query: "ราคาเท่าไหร่?",
+			want: parsedQuery{Clauses: []queryClause{
+				{Text: "ราคาเท่าไหร่?", Kind: clauseTerm, Occur: occurShould},
+			}},
+		},
+		{
+			name:  "wildcard",
+			query: "กระเบื้อง* DB?2",
+			want: parsedQuery{Clauses: []queryClause{
+				{Text: "กระเบื้อง*", Kind: clauseWildcard, Occur: occurShould},
+				{Text: "DB?2", Kind: clauseWildcard, Occur: occurShould},
+			}},
+		},
+		{
+			name:  "* อย่างเดียวถูกข้าม",
+			query: "* ปูน",
+			want: parsedQuery{Clauses: []queryClause{
+				{Text: "ปูน", Kind: clauseTerm, Occur: occurShould},
+			}},
+		},
+		{
+			name:  "ว่าง",
+			query: "   ",
+			want:  parsedQuery{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseQuery(tt.query); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseQuery(%q) = %+v, want %+v", tt.query, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParsedQueryIsEmpty(t *testing.T) {
+	tests := []struct {
+		query string
+		want  bool
+	}{
+		{"ปูน", false},
+		{"+ปูน", false},
+		{`"ปูน ซีเมนต์"`, false},
+		{"file:promotion.md", true},
+		{"-ปูน", true},
+		{"NOT ปูน heading:ราคา", true},
+	}
+
+	for _, tt := range tests {
+		if got := parseQuery(tt.query).isEmpty(); got != tt.want {
+			t.Errorf("parseQuery(%q).isEmpty() = %v, want %v", tt.query, got, tt.want)
+		}
+	}
+}
+
+func TestWildcardRegexp(t *testing.T) {
+	tests := []struct {
+		pattern string
+		text    string
+		want    string
+	}{
+		{"กระเบื้อง*", "กระเบื้องหลังคา scg", "กระเบื้องหลังคา"},
+		{"db?2", "เหล็กเส้น db12 ราคา", "db12"},
+		{"a.b*", "axb a.bc", "a.bc"},
+		{"db?2", "db2", ""},
+	}
+
+	for _, tt := range tests {
+		if got := wildcardRegexp(tt.pattern).FindString(tt.text); got != tt.want {
+			t.Errorf("wildcardRegexp(%q) ใน %q = %q, want %q", tt.pattern, tt.text, got, tt.want)
+		}
+		if wildcardRegexp(tt.pattern) != wildcardRegexp(tt.pattern) {
+			t.Errorf("wildcardRegexp(%q) ไม่ได้ใช้ regexp จาก cache", tt.pattern)
+		}
+	}
+}
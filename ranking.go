@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// ค่าพารามิเตอร์มาตรฐานของ BM25
+const (
+	bm25K1 = 1.2
+	bm25B  = 0.75
+)
+
+// scoreMatchesBM25 ให้คะแนน BM25 แต่ละบรรทัดรวมจากทุกคำค้นหาที่เจอในบรรทัดนั้น
+// คะแนนของแต่ละคำคูณด้วยน้ำหนักของหมวดคำ (คำค้นหาเดิม > คำที่ LLM เดา) คำที่ไม่มีใน weights ใช้น้ำหนัก 1
+// ทุก Match ของบรรทัดเดียวกันจะได้คะแนน รายการคำค้นหา และตำแหน่งที่เจอชุดเดียวกัน
+func scoreMatchesBM25(matchesByKeyword map[string][]Match, weights map[string]float64, stats corpusStats) []Match {
+	type lineScore struct {
+		score    float64
+		keywords []string
+		hits     []Hit
+	}
+	lines := make(map[string]*lineScore)
+
+	// เรียงคำค้นหาเพื่อให้ลำดับ keywords คงที่ทุกครั้ง
+	keywords := make([]string, 0, len(matchesByKeyword))
+	for kw := range matchesByKeyword {
+		keywords = append(keywords, kw)
+	}
+	sort.Strings(keywords)
+
+	var all []Match
+	for _, kw := range keywords {
+		matches := matchesByKeyword[kw]
+		idf := bm25IDF(stats.Lines, len(matches))
+		weight, exists := weights[kw]
+		if !exists {
+			weight = 1
+		}
+
+		for _, match := range matches {
+			tf := len(findKeywordHits(match.Context[match.MatchLine], kw))
+			score := weight * idf * bm25TermWeight(float64(tf), float64(max(match.LineTokens, 1)), stats.AvgLength)
+
+			key := fmt.Sprintf("%s:%d", match.Filename, match.LineNum)
+			ls, exists := lines[key]
+			if !exists {
+				ls = &lineScore{}
+				lines[key] = ls
+			}
+			ls.score += score
+			ls.keywords = append(ls.keywords, kw)
+			ls.hits = append(ls.hits, match.Hits...)
+
+			all = append(all, match)
+		}
+	}
+
+	for i := range all {
+		ls := lines[fmt.Sprintf("%s:%d", all[i].Filename, all[i].LineNum)]
+		all[i].Score = ls.score
+		all[i].Keywords = ls.keywords
+		all[i].Hits = ls.hits
+	}
+	return all
+}
+
+// bm25IDF คำนวณ inverse document frequency (รูปแบบที่ไม่ติดลบ)
+func bm25IDF(totalLines, docFreq int) float64 {
+	n := float64(max(totalLines, docFreq))
+	df := float64(docFreq)
+	return math.Log(1 + (n-df+0.5)/(df+0.5))
+}
+
+// bm25TermWeight คำนวณส่วน term frequency พร้อม normalize ตามความยาวบรรทัด
+func bm25TermWeight(tf, length, avgLength float64) float64 {
+	if tf == 0 {
+		return 0
+	}
+	if avgLength <= 0 {
+		avgLength = length
+	}
+	return tf * (bm25K1 + 1) / (tf + bm25K1*(1-bm25B+bm25B*length/avgLength))
+}
+
+// sortMatchesByScore เรียงผลลัพธ์ตามคะแนนมากไปน้อย (คะแนนเท่ากันเรียงตามไฟล์และบรรทัด)
+func sortMatchesByScore(matches []Match) {
+	sort.SliceStable(matches, func(i, j int) bool {
+		if matches[i].Score != matches[j].Score {
+			return matches[i].Score > matches[j].Score
+		}
+		if matches[i].Filename != matches[j].Filename {
+			return matches[i].Filename < matches[j].Filename
+		}
+		return matches[i].LineNum < matches[j].LineNum
+	})
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestScoreMatchesBM25Order(t *testing.T) {
+	type testLine struct {
+		file   string
+		text   string
+		tokens int
+	}
+	tests := []struct {
+		name     string
+		keywords []string
+		weights  map[string]float64
+		lines    []testLine
+		want     []string // ไฟล์เรียงตามคะแนนมากไปน้อย
+	}{
+		{
+			name:     "tf มากกว่าได้คะแนนสูงกว่า",
+			keywords: []string{"ปูน"},
+			lines: []testLine{
+				{"b.md", "ปูน ทราย หิน", 3},
+				{"a.md", "ปูน ปูน ปูน", 3},
+			},
+			want: []string{"a.md", "b.md"},
+		},
+		{
+			name:     "บรรทัดสั้นได้คะแนนสูงกว่าเมื่อ tf เท่ากัน",
+			keywords: []string{"ปูน"},
+			lines: []testLine{
+				{"long.md", "ปูน ราคา ส่ง ถึง หน้า งาน ทุก วัน ไม่ มี", 10},
+				{"short.md", "ปูน ราคา", 2},
+			},
+			want: []string{"short.md", "long.md"},
+		},
+		{
+			name:     "คำที่เจอน้อยบรรทัดได้คะแนนสูงกว่า",
+			keywords: []string{"ปูน", "โจตัน"},
+			lines: []testLine{
+				{"a.md", "ปูน ถุง", 2},
+				{"b.md", "ปูน ผสม", 2},
+				{"c.md", "สี โจตัน", 2},
+				{"d.md", "ปูน ขาว", 2},
+			},
+			want: []string{"c.md", "a.md", "b.md", "d.md"},
+		},
+		{
+			name:     "บรรทัดที่มีหลายคำค้นหาได้คะแนนรวม",
+			keywords: []string{"ปูน", "ทราย"},
+			lines: []testLine{
+				{"a.md", "ปูน ถุง ใหญ่", 3},
+				{"b.md", "ปูน ทราย หิน", 3},
+				{"c.md", "ทราย ถุง ใหญ่", 3},
+			},
+			want: []string{"b.md", "a.md", "c.md"},
+		},
+		{
+			name:     "น้ำหนักหมวดคำ",
+			keywords: []string{"ปูน", "ซีเมนต์"},
+			weights:  map[string]float64{"ปูน": 1, "ซีเมนต์": 0.3},
+			lines: []testLine{
+				{"a.md", "ซีเมนต์ ถุง", 2},
+				{"b.md", "ปูน ถุง", 2},
+			},
+			want: []string{"b.md", "a.md"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			matchesByKeyword := make(map[string][]Match)
+			for _, kw := range tt.keywords {
+				for _, line := range tt.lines {
+					if strings.Contains(line.text, kw) {
+						matchesByKeyword[kw] = append(matchesByKeyword[kw], Match{
+							Filename:   line.file,
+							LineNum:    1,
+							Context:    []string{line.text},
+							LineTokens: line.tokens,
+						})
+					}
+				}
+			}
+
+			matches := scoreMatchesBM25(matchesByKeyword, tt.weights, corpusStats{Lines: 100, AvgLength: 4})
+			sortMatchesByScore(matches)
+
+			var got []string
+			seen := make(map[string]bool)
+			for _, match := range matches {
+				if !seen[match.Filename] {
+					seen[match.Filename] = true
+					got = append(got, match.Filename)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ลำดับ = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScoreMatchesBM25SameLine(t *testing.T) {
+	// ทุก Match ของบรรทัดเดียวกันต้องได้คะแนนและรายการคำค้นหาชุดเดียวกัน
+	line := Match{Filename: "a.md", LineNum: 3, Context: []string{"ปูน ทราย"}, LineTokens: 2}
+	matches := scoreMatchesBM25(map[string][]Match{"ปูน": {line}, "ทราย": {line}}, nil, corpusStats{Lines: 10, AvgLength: 2})
+
+	if len(matches) != 2 {
+		t.Fatalf("ได้ %d matches, want 2", len(matches))
+	}
+	if matches[0].Score != matches[1].Score || matches[0].Score <= 0 {
+		t.Errorf("คะแนน = %v, %v, want เท่ากันและมากกว่า 0", matches[0].Score, matches[1].Score)
+	}
+	for _, match := range matches {
+		if want := []string{"ทราย", "ปูน"}; !reflect.DeepEqual(match.Keywords, want) {
+			t.Errorf("Keywords = %q, want %q", match.Keywords, want)
+		}
+	}
+}
+
+func TestBM25IDF(t *testing.T) {
+	tests := []struct {
+		name            string
+		lines, df, less int // idf(lines, df) ต้องน้อยกว่า idf(lines, less)
+	}{
+		{"เจอบ่อยได้ idf น้อยกว่า", 100, 50, 5},
+		{"เจอทุกบรรทัดยังน้อยกว่าเจอบางบรรทัด", 100, 100, 99},
+		{"df มากกว่าจำนวนบรรทัด", 10, 20, 10},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, other := bm25IDF(tt.lines, tt.df), bm25IDF(tt.lines, tt.less)
+			if got <= 0 {
+				t.Errorf("bm25IDF(%d, %d) = %v, want > 0", tt.lines, tt.df, got)
+			}
+			if got >= other {
+				t.Errorf("bm25IDF(%d, %d) = %v, want < bm25IDF(%d, %d) = %v", tt.lines, tt.df, got, tt.lines, tt.less, other)
+			}
+		})
+	}
+}
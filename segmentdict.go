@@ -0,0 +1,375 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"hash/fnv"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	m "github.com/veer66/mapkha"
+)
+
+// wordListFileName ชื่อไฟล์คำศัพท์เพิ่มเติมสำหรับตัดคำ: SegmentDictDir/words.txt (ทุก shop) และ SegmentDictDir/<shopid>/words.txt
+// รูปแบบ: 1 บรรทัด = 1 คำ, บรรทัดที่ขึ้นต้นด้วย # คือ comment
+// คำเหล่านี้รวมกับพจนานุกรมมาตรฐานของ mapkha เพื่อไม่ให้ชื่อสินค้า/ศัพท์เฉพาะ เช่น "ปูนกาวกระเบื้อง" ถูกตัดเป็นเศษคำ
+const wordListFileName = "words.txt"
+
+// mapkhaModule module ที่มีไฟล์พจนานุกรมมาตรฐาน tdict-std.txt
+const mapkhaModule = "github.com/veer66/mapkha"
+
+type wordListFile struct {
+	modTime time.Time
+	words   []string
+}
+
+// segmentDictionary พจนานุกรมตัดคำ: คำมาตรฐาน + คำของทุก shop + คำของแต่ละ shop
+// wordcutter ของแต่ละ shop สร้างเมื่อใช้ครั้งแรก และล้างทิ้งเมื่อไฟล์คำศัพท์เปลี่ยน
+type segmentDictionary struct {
+	mu       sync.RWMutex
+	writeMu  sync.Mutex // ให้การแก้ไฟล์จาก admin (อ่าน → เขียน → rename) ทำทีละครั้ง
+	dir      string
+	base     []string
+	files    map[string]wordListFile // "" = ไฟล์กลาง
+	cutters  map[string]*m.Wordcut
+	known    map[string]map[string]bool // shop → คำทั้งหมดในพจนานุกรม (ใช้แสดงใน preview)
+	baseHash uint64
+}
+
+var segmentDict *segmentDictionary
+
+func newSegmentDictionary(dir string, base []string) *segmentDictionary {
+	h := fnv.New64a()
+	for _, word := range base {
+		h.Write([]byte(word))
+		h.Write([]byte{'\n'})
+	}
+	return &segmentDictionary{
+		dir:      dir,
+		base:     base,
+		files:    make(map[string]wordListFile),
+		cutters:  make(map[string]*m.Wordcut),
+		known:    make(map[string]map[string]bool),
+		baseHash: h.Sum64(),
+	}
+}
+
+// path ตำแหน่งไฟล์ของ shop ("" = ไฟล์กลาง)
+func (d *segmentDictionary) path(shopID string) string {
+	return filepath.Join(d.dir, shopID, wordListFileName)
+}
+
+// ready มีคำให้ตัดหรือไม่ (ไม่มี = ใช้การแบ่งคำแบบง่าย)
+func (d *segmentDictionary) ready() bool {
+	if d == nil {
+		return false
+	}
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return len(d.base) > 0 || len(d.files) > 0
+}
+
+// markStale ให้ reload ครั้งถัดไปอ่านไฟล์ของ shop ใหม่ แม้ modTime ไม่เปลี่ยน
+// (admin เขียนไฟล์ 2 ครั้งติดกันอาจได้ modTime เท่าเดิมบนระบบไฟล์ที่เก็บเวลาละเอียดไม่พอ)
+func (d *segmentDictionary) markStale(shopID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if file, exists := d.files[shopID]; exists {
+		file.modTime = time.Time{}
+		d.files[shopID] = file
+	}
+}
+
+// reload โหลดเฉพาะไฟล์ที่เพิ่ม/แก้ไข และลบไฟล์ที่หายไป คืน shop ที่เปลี่ยน ("" = ไฟล์กลาง มีผลทุก shop)
+func (d *segmentDictionary) reload() ([]string, error) {
+	shops := []string{""}
+	entries, err := os.ReadDir(d.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && shopIDPattern.MatchString(entry.Name()) {
+			shops = append(shops, entry.Name())
+		}
+	}
+
+	var changed []string
+	seen := make(map[string]bool)
+	for _, shopID := range shops {
+		info, err := os.Stat(d.path(shopID))
+		if err != nil {
+			continue
+		}
+		seen[shopID] = true
+
+		d.mu.RLock()
+		current, exists := d.files[shopID]
+		d.mu.RUnlock()
+		if exists && current.modTime.Equal(info.ModTime()) {
+			continue
+		}
+
+		words, err := readWordList(d.path(shopID))
+		if err != nil {
+			log.Printf("⚠️  โหลดคำศัพท์ตัดคำ %s ไม่สำเร็จ: %v", d.path(shopID), err)
+			continue
+		}
+		d.mu.Lock()
+		d.files[shopID] = wordListFile{modTime: info.ModTime(), words: words}
+		d.mu.Unlock()
+		log.Printf("✂️  โหลดคำศัพท์ตัดคำ %s (%d คำ)", d.path(shopID), len(words))
+		changed = append(changed, shopID)
+	}
+
+	d.mu.Lock()
+	for shopID := range d.files {
+		if !seen[shopID] {
+			delete(d.files, shopID)
+			changed = append(changed, shopID)
+		}
+	}
+	for _, shopID := range changed {
+		if shopID == "" {
+			// ไฟล์กลางมีผลกับทุก shop
+			d.cutters = make(map[string]*m.Wordcut)
+			d.known = make(map[string]map[string]bool)
+			break
+		}
+		delete(d.cutters, shopID)
+		delete(d.known, shopID)
+	}
+	d.mu.Unlock()
+
+	return changed, nil
+}
+
+// watch ตรวจการแก้ไขไฟล์คำศัพท์เป็นระยะ เอกสารของ shop ที่คำศัพท์เปลี่ยนจะถูก index ใหม่
+// ในรอบถัดไปของ docIndex.watch (ดู signature)
+func (d *segmentDictionary) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		changed, err := d.reload()
+		if err != nil {
+			log.Printf("⚠️  อัปเดตคำศัพท์ตัดคำไม่สำเร็จ: %v", err)
+			continue
+		}
+		if len(changed) > 0 {
+			log.Printf("✂️  คำศัพท์ตัดคำเปลี่ยน (shop: %q) → จะ index เอกสารใหม่", changed)
+		}
+	}
+}
+
+// wordsLocked คำทั้งหมดที่ใช้ตัดคำของ shop (ต้องถือ lock อยู่แล้ว)
+func (d *segmentDictionary) wordsLocked(shopID string) []string {
+	words := make([]string, 0, len(d.base)+len(d.files[""].words)+len(d.files[shopID].words))
+	words = append(words, d.base...)
+	words = append(words, d.files[""].words...)
+	if shopID != "" {
+		words = append(words, d.files[shopID].words...)
+	}
+	return words
+}
+
+// cutterKey shop ที่ไม่มีไฟล์ของตัวเองใช้ wordcutter กลางร่วมกัน
+func (d *segmentDictionary) cutterKey(shopID string) string {
+	if _, exists := d.files[shopID]; exists {
+		return shopID
+	}
+	return ""
+}
+
+// cutter wordcutter ของ shop (nil = ไม่มีพจนานุกรม)
+func (d *segmentDictionary) cutter(shopID string) *m.Wordcut {
+	if d == nil {
+		return nil
+	}
+
+	d.mu.RLock()
+	key := d.cutterKey(shopID)
+	cutter, exists := d.cutters[key]
+	d.mu.RUnlock()
+	if exists {
+		return cutter
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if cutter, exists := d.cutters[key]; exists {
+		return cutter
+	}
+	if words := d.wordsLocked(key); len(words) > 0 {
+		start := time.Now()
+		cutter = m.NewWordcut(m.MakeDict(uniqueWords(words)))
+		log.Printf("✂️  สร้าง wordcutter (shop: %q, %d คำ, %v)", key, len(words), time.Since(start))
+	}
+	d.cutters[key] = cutter
+	return cutter
+}
+
+// isKnown คำนี้อยู่ในพจนานุกรมของ shop หรือไม่
+func (d *segmentDictionary) isKnown(shopID, word string) bool {
+	if d == nil {
+		return false
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	key := d.cutterKey(shopID)
+	known, exists := d.known[key]
+	if !exists {
+		known = make(map[string]bool)
+		for _, w := range d.wordsLocked(key) {
+			known[w] = true
+		}
+		d.known[key] = known
+	}
+	return known[word]
+}
+
+// signature ค่าที่เปลี่ยนเมื่อคำศัพท์ของ shop เปลี่ยน (เก็บไว้กับเอกสารใน index เพื่อรู้ว่าต้องตัดคำใหม่)
+func (d *segmentDictionary) signature(shopID string) string {
+	if !d.ready() {
+		return "simple"
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	h := fnv.New64a()
+	for _, file := range []string{"", d.cutterKey(shopID)} {
+		for _, word := range d.files[file].words {
+			h.Write([]byte(word))
+			h.Write([]byte{'\n'})
+		}
+	}
+	return fmt.Sprintf("%x-%x", d.baseHash, h.Sum64())
+}
+
+// words คำศัพท์เพิ่มเติมของไฟล์ ("" = ไฟล์กลาง)
+func (d *segmentDictionary) words(shopID string) []string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.files[shopID].words
+}
+
+// addWords เพิ่มคำต่อท้ายไฟล์ (ข้ามคำที่มีอยู่แล้ว) แล้วโหลดใหม่ คืนคำที่เพิ่มจริง
+func (d *segmentDictionary) addWords(shopID string, words []string) ([]string, error) {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	existing := make(map[string]bool)
+	for _, word := range d.words(shopID) {
+		existing[word] = true
+	}
+
+	var added []string
+	for _, word := range words {
+		word = normalizeText(strings.TrimSpace(word))
+		if word == "" {
+			continue
+		}
+		if strings.ContainsAny(word, " \t\r\n") || !hasThaiCharacters(word) {
+			return nil, fmt.Errorf("คำต้องเป็นภาษาไทยและไม่มีช่องว่าง: %q", word)
+		}
+		if !existing[word] {
+			existing[word] = true
+			added = append(added, word)
+		}
+	}
+	if len(added) == 0 {
+		return nil, nil
+	}
+
+	if err := appendLines(d.path(shopID), added); err != nil {
+		return nil, err
+	}
+
+	d.markStale(shopID)
+	_, err := d.reload()
+	return added, err
+}
+
+// removeWord ลบคำออกจากไฟล์ (comment และคำอื่นยังอยู่) คืนจำนวนบรรทัดที่ลบ
+func (d *segmentDictionary) removeWord(shopID, word string) (int, error) {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	path := d.path(shopID)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	word = normalizeText(strings.TrimSpace(word))
+	var kept []string
+	removed := 0
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		if normalizeText(strings.TrimSpace(line)) == word {
+			removed++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	if err := replaceFile(path, []byte(strings.Join(kept, "\n")+"\n")); err != nil {
+		return 0, err
+	}
+
+	d.markStale(shopID)
+	_, err = d.reload()
+	return removed, err
+}
+
+// readWordList อ่านไฟล์คำศัพท์ 1 คำต่อบรรทัด (normalize เหมือนข้อความในเอกสาร)
+func readWordList(path string) ([]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var words []string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		words = append(words, normalizeText(line))
+	}
+	return words, scanner.Err()
+}
+
+// loadBaseWords อ่านพจนานุกรมมาตรฐานจาก SegmentBaseDict (Docker image คัดลอก tdict-std.txt ของ mapkha ไว้ที่ ./dict/)
+// ไม่ค้นหาใน module cache เพราะ binary ที่ deploy ไม่มี module cache
+func loadBaseWords(path string) ([]string, string, error) {
+	if path == "" {
+		return nil, path, fmt.Errorf("ไม่ได้ตั้ง SEGMENT_BASE_DICT")
+	}
+	words, err := readWordList(path)
+	if err != nil {
+		return nil, path, fmt.Errorf("อ่านพจนานุกรมมาตรฐาน SEGMENT_BASE_DICT=%s ไม่ได้: %w (คัดลอก tdict-std.txt จาก module %s มาไว้ที่ path นี้ เหมือนใน Dockerfile)", path, err, mapkhaModule)
+	}
+	return words, path, nil
+}
+
+func uniqueWords(words []string) []string {
+	sorted := append([]string(nil), words...)
+	sort.Strings(sorted)
+	unique := sorted[:0]
+	for i, word := range sorted {
+		if i == 0 || word != sorted[i-1] {
+			unique = append(unique, word)
+		}
+	}
+	return unique
+}
@@ -0,0 +1,45 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSegmentDictionaryAddRemoveWords(t *testing.T) {
+	dict := newSegmentDictionary(t.TempDir(), nil)
+	path := dict.path("shop001")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// ไฟล์ที่แก้ด้วยมือและไม่มี \n ท้ายไฟล์
+	if err := os.WriteFile(path, []byte("# คำของร้าน\nโจตัน"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := dict.reload(); err != nil {
+		t.Fatal(err)
+	}
+
+	added, err := dict.addWords("shop001", []string{"โจตัน", "นํ้ายากันซึม"})
+	if err != nil {
+		t.Fatalf("addWords: %v", err)
+	}
+	if want := []string{"น้ำยากันซึม"}; !reflect.DeepEqual(added, want) {
+		t.Errorf("addWords = %q, want %q", added, want)
+	}
+	if want := []string{"โจตัน", "น้ำยากันซึม"}; !reflect.DeepEqual(dict.words("shop001"), want) {
+		t.Errorf("words หลังเพิ่ม = %q, want %q", dict.words("shop001"), want)
+	}
+
+	removed, err := dict.removeWord("shop001", "นํ้ายากันซึม")
+	if err != nil {
+		t.Fatalf("removeWord: %v", err)
+	}
+	if removed != 1 {
+		t.Errorf("removeWord = %d, want 1", removed)
+	}
+	if want := []string{"โจตัน"}; !reflect.DeepEqual(dict.words("shop001"), want) {
+		t.Errorf("words หลังลบ = %q, want %q", dict.words("shop001"), want)
+	}
+}
@@ -0,0 +1,134 @@
+package main
+
+import (
+	"bufio"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+	"unicode/utf8"
+)
+
+// คำที่ไม่ใช้ค้นหาแยก (stopword) อ่านจาก StopwordFile: 1 บรรทัด = 1 คำ ทั้งภาษาไทยและอังกฤษ, # คือ comment
+// ใช้กรองคำที่ได้จากการตัดคำ เช่น "ที่", "และ", "the" ซึ่งเจอเกือบทุกบรรทัด
+// คำค้นหาเดิมของผู้ใช้ไม่ถูกกรอง (ค้นหา "การ" ตรงๆ ก็ยังได้ผล)
+
+// stopwordList รายการ stopword ที่โหลดใหม่เมื่อไฟล์เปลี่ยน
+type stopwordList struct {
+	mu      sync.RWMutex
+	path    string
+	modTime time.Time
+	words   map[string]bool
+}
+
+var stopwords *stopwordList
+
+func newStopwordList(path string) *stopwordList {
+	return &stopwordList{path: path, words: make(map[string]bool)}
+}
+
+// reload โหลดไฟล์ใหม่ถ้าแก้ไข (ไฟล์หาย = ไม่มี stopword) คืน true ถ้ามีการเปลี่ยนแปลง
+func (s *stopwordList) reload() (bool, error) {
+	info, err := os.Stat(s.path)
+	if os.IsNotExist(err) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		changed := len(s.words) > 0
+		s.words, s.modTime = make(map[string]bool), time.Time{}
+		return changed, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	s.mu.RLock()
+	unchanged := s.modTime.Equal(info.ModTime())
+	s.mu.RUnlock()
+	if unchanged {
+		return false, nil
+	}
+
+	file, err := os.Open(s.path)
+	if err != nil {
+		return false, err
+	}
+	defer file.Close()
+
+	words := make(map[string]bool)
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		word := normalizeText(strings.TrimSpace(scanner.Text()))
+		if word != "" && !strings.HasPrefix(word, "#") {
+			words[word] = true
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return false, err
+	}
+
+	s.mu.Lock()
+	s.words, s.modTime = words, info.ModTime()
+	s.mu.Unlock()
+	log.Printf("🚫 โหลด stopword %s (%d คำ)", s.path, len(words))
+	return true, nil
+}
+
+// watch ตรวจการแก้ไขไฟล์ stopword เป็นระยะ
+func (s *stopwordList) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.reload(); err != nil {
+			log.Printf("⚠️  อัปเดต stopword ไม่สำเร็จ: %v", err)
+		}
+	}
+}
+
+// contains คำนี้เป็น stopword หรือไม่ (ไม่สนตัวพิมพ์/รูปแบบการพิมพ์)
+func (s *stopwordList) contains(word string) bool {
+	if s == nil {
+		return false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.words[normalizeText(strings.TrimSpace(word))]
+}
+
+// isSearchableKeyword คำที่ได้จากการตัดคำต้องยาวพอและไม่ใช่ stopword
+// ความยาวนับเป็นจำนวนอักขระ ไม่ใช่ byte (อักษรไทย 1 ตัวยาว 3 byte จึงผ่านเงื่อนไข >= 2 byte เดิมได้)
+func isSearchableKeyword(word string) bool {
+	return utf8.RuneCountInString(strings.TrimSpace(word)) >= cfg.MinKeywordLength && !stopwords.contains(word)
+}
+
+// weightSegmentKeywords ถ่วงน้ำหนักคำที่ได้จากการตัดคำด้วย IDF ในเอกสารของ shop
+// df คือจำนวนบรรทัดที่ค้นเจอแล้วใน textSearch (ไม่ต้องค้นซ้ำ)
+// คำที่อยู่ในบรรทัดมากกว่า KeywordMaxLineRatio ถูกตัดทิ้ง เฉพาะเมื่อ shop มีอย่างน้อย KeywordMinLines บรรทัด
+// (shop ที่มีเอกสารน้อย สัดส่วนไม่มีความหมาย เช่น 1 ใน 3 บรรทัด) ที่เหลือคูณน้ำหนักด้วย IDF/IDF สูงสุด
+func weightSegmentKeywords(keywords []searchKeyword, matchesByKeyword map[string][]Match, stats corpusStats) []searchKeyword {
+	if stats.Lines == 0 {
+		return keywords
+	}
+	maxIDF := bm25IDF(stats.Lines, 0)
+
+	kept := keywords[:0]
+	for _, kw := range keywords {
+		if kw.Category != keywordSegment {
+			kept = append(kept, kw)
+			continue
+		}
+
+		df := len(matchesByKeyword[kw.Text])
+		if ratio := float64(df) / float64(stats.Lines); stats.Lines >= cfg.KeywordMinLines && ratio > cfg.KeywordMaxLineRatio {
+			log.Printf("   🚫 ข้ามคำที่พบบ่อยเกินไป %q (%d/%d บรรทัด)", kw.Text, df, stats.Lines)
+			continue
+		}
+		kw.Weight *= bm25IDF(stats.Lines, df) / maxIDF
+		kept = append(kept, kw)
+	}
+
+	sort.SliceStable(kept, func(i, j int) bool { return kept[i].Weight > kept[j].Weight })
+	return kept
+}
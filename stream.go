@@ -0,0 +1,166 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Citation แหล่งที่มาของคำตอบ (ส่งใน event "done")
+type Citation struct {
+	Filename string `json:"filename"`
+	Path     string `json:"path"`
+	LineNum  int    `json:"line_number"`
+	Chunk    int    `json:"chunk,omitempty"`
+}
+
+// sseWriter เขียน Server-Sent Events และ flush ทันที
+type sseWriter struct {
+	w       http.ResponseWriter
+	flusher http.Flusher
+}
+
+func (s *sseWriter) send(event string, data interface{}) error {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", event, payload); err != nil {
+		return err
+	}
+	s.flusher.Flush()
+	return nil
+}
+
+// streamSearchHandler ค้นหาแล้วส่งผลแบบ SSE: results → token (ทีละส่วนของสรุป) → done (พร้อม citations)
+func streamSearchHandler(w http.ResponseWriter, r *http.Request) {
+	enableCORSSimple(w)
+	if r.Method == "OPTIONS" {
+		return
+	}
+
+	if r.Method != "POST" {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", http.StatusInternalServerError)
+		return
+	}
+
+	req, status, err := decodeSearchRequest(r)
+	if err != nil {
+		writeSearchResponse(w, status, SearchResponseSimple{Error: err.Error()})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.Header().Set("X-Accel-Buffering", "no")
+	sse := &sseWriter{w: w, flusher: flusher}
+
+	uniqueMatches, err := executeSearch(r.Context(), req)
+	if err != nil {
+		sse.send("error", map[string]string{"error": err.Error()})
+		return
+	}
+
+	results := toSearchResults(uniqueMatches, req)
+	if err := sse.send("results", SearchResponseSimple{
+		Query:   req.Query,
+		Mode:    req.Mode,
+		ShopID:  req.ShopID,
+		Results: results,
+		Total:   len(results),
+	}); err != nil {
+		return
+	}
+
+	done := map[string]interface{}{"citations": buildCitations(uniqueMatches)}
+
+	if req.UseSummary && len(uniqueMatches) > 0 {
+		log.Printf("🤖 กำลังสรุปผลด้วย AI (stream)...")
+		contextForAI := formatMatchesForAI(uniqueMatches, req.Query)
+		sourceInfo := buildSourceInfo(uniqueMatches)
+
+		provider, summary, err := streamSummary(r.Context(), contextForAI, req.Query, sourceInfo, func(token string) error {
+			return sse.send("token", map[string]string{"text": token})
+		})
+		if err != nil {
+			log.Printf("❌ stream สรุปผลไม่สำเร็จ: %v", err)
+			sse.send("error", map[string]string{"error": err.Error()})
+			return
+		}
+		done["summary"] = summary
+		done["provider"] = provider
+	}
+
+	sse.send("done", done)
+}
+
+// streamSummary ลอง provider ตามลำดับ ถ้า provider รองรับ stream จะส่ง token ทันที
+// ไม่เช่นนั้นส่งคำตอบทั้งก้อนเป็น token เดียว (เปลี่ยน provider ได้เฉพาะเมื่อยังไม่ได้ส่ง token ใดออกไป)
+func streamSummary(ctx context.Context, contextText, query, sourceInfo string, onToken func(string) error) (string, string, error) {
+	prompt := buildSummaryPrompt(query, contextText+sourceInfo)
+
+	for _, provider := range llmProviders {
+		providerCtx, cancel := context.WithTimeout(ctx, time.Duration(cfg.LLMTimeout)*time.Second)
+
+		var builder strings.Builder
+		var err error
+		if streamer, ok := provider.(StreamingLLMProvider); ok {
+			err = streamer.GenerateStream(providerCtx, prompt, func(token string) error {
+				builder.WriteString(token)
+				return onToken(token)
+			})
+		} else {
+			var summary string
+			summary, err = provider.Generate(providerCtx, prompt)
+			if err == nil && strings.TrimSpace(summary) != "" {
+				builder.WriteString(summary)
+				err = onToken(summary)
+			}
+		}
+		cancel()
+		if err == nil && strings.TrimSpace(builder.String()) == "" {
+			err = fmt.Errorf("คำตอบว่าง")
+		}
+
+		if err == nil {
+			log.Printf("✅ ใช้ %s สรุปผลสำเร็จ (stream)", provider.Name())
+			return provider.Name(), builder.String(), nil
+		}
+		if builder.Len() > 0 {
+			// ส่งบางส่วนออกไปแล้ว เปลี่ยน provider ไม่ได้
+			return provider.Name(), builder.String(), fmt.Errorf("%s หยุดกลางคัน: %w", provider.Name(), err)
+		}
+		log.Printf("⚠️  %s ล้มเหลว: %v", provider.Name(), err)
+	}
+
+	return "", "", fmt.Errorf("LLM ทุกตัวล้มเหลว (%d providers)", len(llmProviders))
+}
+
+// buildCitations แหล่งที่มาของผลลัพธ์ที่ส่งให้ AI (จำนวนเท่ากับ buildSourceInfo)
+func buildCitations(matches []Match) []Citation {
+	citations := make([]Citation, 0, min(len(matches), maxSummarySources))
+	for i, match := range matches {
+		if i >= maxSummarySources {
+			break
+		}
+		citations = append(citations, Citation{
+			Filename: filepath.Base(match.Filename),
+			Path:     relativeDocPath(match.Filename),
+			LineNum:  match.LineNum,
+			Chunk:    match.Chunk,
+		})
+	}
+	return citations
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
+)
+
+// synonymFileName ชื่อไฟล์พจนานุกรมคำพ้อง: SynonymDir/synonyms.tsv (ทุก shop) และ SynonymDir/<shopid>/synonyms.tsv
+// รูปแบบ: 1 บรรทัด = 1 กลุ่มคำที่ใช้แทนกันได้ คั่นด้วย tab, บรรทัดที่ขึ้นต้นด้วย # คือ comment
+const synonymFileName = "synonyms.tsv"
+
+type synonymFile struct {
+	modTime time.Time
+	groups  [][]string
+}
+
+// synonymDictionary พจนานุกรมคำพ้อง/ชื่อเรียกอื่นแยกตาม shop ("" = ใช้ทุก shop)
+type synonymDictionary struct {
+	mu      sync.RWMutex
+	writeMu sync.Mutex // ให้การแก้ไฟล์จาก admin (อ่าน → เขียน → rename) ทำทีละครั้ง
+	dir     string
+	files   map[string]synonymFile
+}
+
+var synonymDict *synonymDictionary
+
+func newSynonymDictionary(dir string) *synonymDictionary {
+	return &synonymDictionary{dir: dir, files: make(map[string]synonymFile)}
+}
+
+// path ตำแหน่งไฟล์ของ shop ("" = ไฟล์กลาง)
+func (d *synonymDictionary) path(shopID string) string {
+	return filepath.Join(d.dir, shopID, synonymFileName)
+}
+
+// markStale ให้ reload ครั้งถัดไปอ่านไฟล์ของ shop ใหม่ แม้ modTime ไม่เปลี่ยน
+// (admin เขียนไฟล์ 2 ครั้งติดกันอาจได้ modTime เท่าเดิมบนระบบไฟล์ที่เก็บเวลาละเอียดไม่พอ)
+func (d *synonymDictionary) markStale(shopID string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if file, exists := d.files[shopID]; exists {
+		file.modTime = time.Time{}
+		d.files[shopID] = file
+	}
+}
+
+// reload โหลดเฉพาะไฟล์ที่เพิ่ม/แก้ไข และลบไฟล์ที่หายไป คืนจำนวนไฟล์ที่เปลี่ยน
+func (d *synonymDictionary) reload() (int, error) {
+	shops := []string{""}
+	entries, err := os.ReadDir(d.dir)
+	if err != nil && !os.IsNotExist(err) {
+		return 0, err
+	}
+	for _, entry := range entries {
+		if entry.IsDir() && shopIDPattern.MatchString(entry.Name()) {
+			shops = append(shops, entry.Name())
+		}
+	}
+
+	changed := 0
+	seen := make(map[string]bool)
+	for _, shopID := range shops {
+		info, err := os.Stat(d.path(shopID))
+		if err != nil {
+			continue
+		}
+		seen[shopID] = true
+
+		d.mu.RLock()
+		current, exists := d.files[shopID]
+		d.mu.RUnlock()
+		if exists && current.modTime.Equal(info.ModTime()) {
+			continue
+		}
+
+		groups, err := readSynonymFile(d.path(shopID))
+		if err != nil {
+			log.Printf("⚠️  โหลดพจนานุกรมคำพ้อง %s ไม่สำเร็จ: %v", d.path(shopID), err)
+			continue
+		}
+		d.mu.Lock()
+		d.files[shopID] = synonymFile{modTime: info.ModTime(), groups: groups}
+		d.mu.Unlock()
+		log.Printf("📖 โหลดพจนานุกรมคำพ้อง %s (%d กลุ่ม)", d.path(shopID), len(groups))
+		changed++
+	}
+
+	d.mu.Lock()
+	for shopID := range d.files {
+		if !seen[shopID] {
+			delete(d.files, shopID)
+			changed++
+		}
+	}
+	d.mu.Unlock()
+
+	return changed, nil
+}
+
+// watch ตรวจการแก้ไขไฟล์พจนานุกรมเป็นระยะ (แก้ไฟล์แล้วไม่ต้อง restart)
+func (d *synonymDictionary) watch(interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := d.reload(); err != nil {
+			log.Printf("⚠️  อัปเดตพจนานุกรมคำพ้องไม่สำเร็จ: %v", err)
+		}
+	}
+}
+
+// expand หากลุ่มคำที่มีคำใดคำหนึ่งอยู่ในข้อความ (ไม่สนตัวพิมพ์) แล้วคืนคำอื่นในกลุ่มนั้น
+// คำภาษาไทยเทียบแบบ substring ส่วนคำอื่นต้องเป็นทั้งคำ (ไม่ให้ "OT" ตรงกับ "Jotun")
+// ใช้ทั้งไฟล์กลางและไฟล์ของ shop
+func (d *synonymDictionary) expand(shopID, text string) []string {
+	if d == nil {
+		return nil
+	}
+	lower := normalizeText(text)
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var result []string
+	for _, file := range []string{"", shopID} {
+		for _, group := range d.files[file].groups {
+			matched := false
+			for _, term := range group {
+				if utf8.RuneCountInString(term) >= 2 && containsTerm(lower, normalizeText(term)) {
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				continue
+			}
+			for _, term := range group {
+				if !containsTerm(lower, normalizeText(term)) {
+					result = append(result, term)
+				}
+			}
+		}
+	}
+
+	if len(result) > 0 {
+		log.Printf("📖 พจนานุกรมคำพ้อง: %q → %v", text, result)
+	}
+	return result
+}
+
+// groups กลุ่มคำทั้งหมดของไฟล์ ("" = ไฟล์กลาง)
+func (d *synonymDictionary) groups(shopID string) [][]string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.files[shopID].groups
+}
+
+// addGroup เพิ่มกลุ่มคำต่อท้ายไฟล์ (comment เดิมยังอยู่) แล้วโหลดใหม่
+func (d *synonymDictionary) addGroup(shopID string, terms []string) error {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	var cleaned []string
+	for _, term := range terms {
+		term = strings.TrimSpace(term)
+		if strings.ContainsAny(term, "\t\r\n") {
+			return fmt.Errorf("คำต้องไม่มี tab หรือขึ้นบรรทัดใหม่: %q", term)
+		}
+		if term != "" {
+			cleaned = append(cleaned, term)
+		}
+	}
+	if len(cleaned) < 2 {
+		return fmt.Errorf("กลุ่มคำพ้องต้องมีอย่างน้อย 2 คำ")
+	}
+
+	if err := appendLines(d.path(shopID), []string{strings.Join(cleaned, "\t")}); err != nil {
+		return err
+	}
+
+	d.markStale(shopID)
+	_, err := d.reload()
+	return err
+}
+
+// removeTerm ลบทุกกลุ่มที่มีคำนี้ออกจากไฟล์ (เทียบแบบ normalizeText เหมือนตอนค้นหา) คืนจำนวนกลุ่มที่ลบ
+func (d *synonymDictionary) removeTerm(shopID, term string) (int, error) {
+	d.writeMu.Lock()
+	defer d.writeMu.Unlock()
+
+	path := d.path(shopID)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	term = normalizeText(strings.TrimSpace(term))
+	var kept []string
+	removed := 0
+	for _, line := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+		group := parseSynonymLine(line)
+		if containsNormalized(group, term) {
+			removed++
+			continue
+		}
+		kept = append(kept, line)
+	}
+	if removed == 0 {
+		return 0, nil
+	}
+
+	if err := replaceFile(path, []byte(strings.Join(kept, "\n")+"\n")); err != nil {
+		return 0, err
+	}
+
+	d.markStale(shopID)
+	_, err = d.reload()
+	return removed, err
+}
+
+// readSynonymFile อ่านไฟล์ TSV เป็นกลุ่มคำ
+func readSynonymFile(path string) ([][]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var groups [][]string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		if group := parseSynonymLine(scanner.Text()); len(group) >= 2 {
+			groups = append(groups, group)
+		}
+	}
+	return groups, scanner.Err()
+}
+
+// parseSynonymLine แยกบรรทัดเป็นคำ (comment และบรรทัดว่างคืน nil)
+func parseSynonymLine(line string) []string {
+	line = strings.TrimSpace(line)
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	var group []string
+	for _, term := range strings.Split(line, "\t") {
+		if term = strings.TrimSpace(term); term != "" {
+			group = append(group, term)
+		}
+	}
+	return group
+}
+
+// containsNormalized list มีคำที่ normalize แล้วเท่ากับ value (value ต้อง normalize มาแล้ว)
+func containsNormalized(list []string, value string) bool {
+	for _, v := range list {
+		if normalizeText(v) == value {
+			return true
+		}
+	}
+	return false
+}
+
+// appendLines เขียนบรรทัดต่อท้ายไฟล์ ถ้าไฟล์เดิม (ที่อาจแก้ด้วยมือ) ไม่ได้จบด้วย \n ให้ขึ้นบรรทัดใหม่ก่อน
+// ไม่อย่างนั้นบรรทัดใหม่จะไปต่อกับบรรทัดสุดท้ายเดิม
+func appendLines(path string, lines []string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+
+	text := strings.Join(lines, "\n") + "\n"
+	info, err := f.Stat()
+	if err == nil && info.Size() > 0 {
+		last := make([]byte, 1)
+		if _, err = f.ReadAt(last, info.Size()-1); err == nil && last[0] != '\n' {
+			text = "\n" + text
+		}
+	}
+	if err == nil {
+		_, err = f.WriteString(text)
+	}
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	return err
+}
+
+// containsTerm text มี term หรือไม่ (term ที่ไม่ใช่ภาษาไทยต้องไม่ติดกับตัวอักษร/ตัวเลขอื่น)
+func containsTerm(text, term string) bool {
+	if hasThaiCharacters(term) {
+		return strings.Contains(text, term)
+	}
+	for offset := 0; ; {
+		i := strings.Index(text[offset:], term)
+		if i < 0 {
+			return false
+		}
+		start, end := offset+i, offset+i+len(term)
+		before, _ := utf8.DecodeLastRuneInString(text[:start])
+		after, _ := utf8.DecodeRuneInString(text[end:])
+		if !isWordRune(before) && !isWordRune(after) {
+			return true
+		}
+		offset = start + 1
+	}
+}
+
+func isWordRune(r rune) bool {
+	return r != utf8.RuneError && !isThaiChar(r) && (unicode.IsLetter(r) || unicode.IsNumber(r))
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAppendLines(t *testing.T) {
+	tests := []struct {
+		name     string
+		existing string // "-" = ยังไม่มีไฟล์
+		want     string
+	}{
+		{"ไฟล์ใหม่", "-", "ปูน\tซีเมนต์\n"},
+		{"ไฟล์ว่าง", "", "ปูน\tซีเมนต์\n"},
+		{"จบด้วย \\n", "# comment\n", "# comment\nปูน\tซีเมนต์\n"},
+		{"ไม่จบด้วย \\n", "เหล็ก\tเหล็กเส้น", "เหล็ก\tเหล็กเส้น\nปูน\tซีเมนต์\n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "shop001", synonymFileName)
+			if tt.existing != "-" {
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(tt.existing), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if err := appendLines(path, []string{"ปูน\tซีเมนต์"}); err != nil {
+				t.Fatalf("appendLines: %v", err)
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("ไฟล์ = %q, want %q", data, tt.want)
+			}
+		})
+	}
+}
+
+func TestSynonymRemoveTermNormalized(t *testing.T) {
+	dict := newSynonymDictionary(t.TempDir())
+	path := dict.path("shop001")
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		t.Fatal(err)
+	}
+	// น้ำยา พิมพ์ด้วย ํ+้+า และ JOTUN ตัวพิมพ์ใหญ่แบบ full-width
+	content := "นํ้ายากันซึม\tกันซึม\nＪＯＴＵＮ\tโจตัน\nปูน\tซีเมนต์"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		term string
+		want int
+	}{
+		{"น้ำยากันซึม", 1},
+		{"jotun", 1},
+		{"ทราย", 0},
+	}
+	for _, tt := range tests {
+		removed, err := dict.removeTerm("shop001", tt.term)
+		if err != nil {
+			t.Fatalf("removeTerm(%q): %v", tt.term, err)
+		}
+		if removed != tt.want {
+			t.Errorf("removeTerm(%q) = %d, want %d", tt.term, removed, tt.want)
+		}
+	}
+
+	if got := dict.groups("shop001"); len(got) != 1 || got[0][0] != "ปูน" {
+		t.Errorf("groups หลังลบ = %q, want [[ปูน ซีเมนต์]]", got)
+	}
+}
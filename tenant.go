@@ -0,0 +1,69 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// โครงสร้างเอกสารแยกตามร้าน:
+//
+//	doc/<shopid>/...  → เอกสารของ shop นั้น
+//	doc/*.md          → เอกสารของ Config.DefaultShopID
+var shopIDPattern = regexp.MustCompile(`^[A-Za-z0-9_-]{1,50}$`)
+
+// resolveShopID ตรวจสอบ shopid จาก request
+// ไม่ระบุ shopid = error (ไม่ให้ client ที่ลืมส่ง tenant เห็นเอกสารของ shop อื่น)
+// เว้นแต่เปิด AllowDefaultShopID ไว้ จึงใช้ DefaultShopID แทน
+func resolveShopID(shopID string) (string, error) {
+	if shopID == "" {
+		if !cfg.AllowDefaultShopID {
+			return "", fmt.Errorf("ต้องระบุ shopid")
+		}
+		return cfg.DefaultShopID, nil
+	}
+	if !shopIDPattern.MatchString(shopID) {
+		return "", fmt.Errorf("shopid ไม่ถูกต้อง: %s", shopID)
+	}
+	return shopID, nil
+}
+
+// splitDocPath แยก path ของไฟล์ใน index เป็น shopid และ path relative กับโฟลเดอร์ของ shop
+func splitDocPath(root, path string) (shopID, rel string) {
+	rel, err := filepath.Rel(root, path)
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return cfg.DefaultShopID, path
+	}
+
+	parts := strings.SplitN(filepath.ToSlash(rel), "/", 2)
+	if len(parts) == 1 {
+		return cfg.DefaultShopID, rel
+	}
+	return parts[0], filepath.FromSlash(parts[1])
+}
+
+// shopDocFilePath แปลงชื่อไฟล์ของ shop เป็น path ในโฟลเดอร์เอกสาร (ห้ามออกนอกโฟลเดอร์ของ shop)
+func shopDocFilePath(shopID, filename string) (string, error) {
+	clean := filepath.Clean(filepath.FromSlash(filename))
+	if filepath.IsAbs(clean) || clean == ".." || strings.HasPrefix(clean, ".."+string(filepath.Separator)) {
+		return "", fmt.Errorf("ชื่อไฟล์ไม่ถูกต้อง: %s", filename)
+	}
+
+	path := filepath.Join(cfg.DocPath, shopID, clean)
+	if _, err := os.Stat(path); err != nil && shopID == cfg.DefaultShopID {
+		// ไฟล์ของ shop เริ่มต้นอาจอยู่ชั้นบนสุดของ doc/ (ต้องไม่ใช่ไฟล์ในโฟลเดอร์ของ shop อื่น)
+		if !strings.ContainsRune(filepath.ToSlash(clean), '/') {
+			return filepath.Join(cfg.DocPath, clean), nil
+		}
+	}
+	return path, nil
+}
+
+// relativeDocPath path ของไฟล์ relative กับโฟลเดอร์ของ shop (ใช้ / เสมอ)
+// ไฟล์จาก vector search เก็บเป็น path relative อยู่แล้วจึงคืนค่าเดิม
+func relativeDocPath(path string) string {
+	_, rel := splitDocPath(cfg.DocPath, path)
+	return filepath.ToSlash(rel)
+}
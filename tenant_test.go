@@ -0,0 +1,80 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestSplitDocPath(t *testing.T) {
+	defer func(old *Config) { cfg = old }(cfg)
+	cfg = &Config{DocPath: "/docs", DefaultShopID: "shop001"}
+
+	tests := []struct {
+		name       string
+		path       string
+		wantShopID string
+		wantRel    string
+	}{
+		{"ไฟล์ในโฟลเดอร์ของ shop", "/docs/shop002/promotion.md", "shop002", "promotion.md"},
+		{"ไฟล์ในโฟลเดอร์ย่อย", "/docs/shop002/สินค้า/ปูน.md", "shop002", "สินค้า/ปูน.md"},
+		{"ไฟล์ชั้นบนสุดเป็นของ shop เริ่มต้น", "/docs/faq.md", "shop001", "faq.md"},
+		{"path นอกโฟลเดอร์เอกสาร", "/etc/passwd", "shop001", "/etc/passwd"},
+		{"path ที่ย้อนออกนอกโฟลเดอร์", "/docs/../etc/passwd", "shop001", "/docs/../etc/passwd"},
+		{"ชื่อขึ้นต้นด้วย .. แต่อยู่ในโฟลเดอร์", "/docs/..shop/a.md", "..shop", "a.md"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shopID, rel := splitDocPath(cfg.DocPath, tt.path)
+			if shopID != tt.wantShopID || rel != tt.wantRel {
+				t.Errorf("splitDocPath(%q) = %q, %q, want %q, %q", tt.path, shopID, rel, tt.wantShopID, tt.wantRel)
+			}
+		})
+	}
+}
+
+func TestShopDocFilePath(t *testing.T) {
+	defer func(old *Config) { cfg = old }(cfg)
+	root := t.TempDir()
+	cfg = &Config{DocPath: root, DefaultShopID: "shop001"}
+	if err := os.WriteFile(filepath.Join(root, "faq.md"), []byte("# FAQ\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		shopID   string
+		filename string
+		want     string // "" = ต้อง error
+	}{
+		{"ไฟล์ของ shop", "shop002", "promotion.md", "shop002/promotion.md"},
+		{"โฟลเดอร์ย่อย", "shop002", "สินค้า/ปูน.md", "shop002/สินค้า/ปูน.md"},
+		{"clean path ที่ยังอยู่ในโฟลเดอร์", "shop002", "a/../b.md", "shop002/b.md"},
+		{"shop เริ่มต้นใช้ไฟล์ชั้นบนสุด", "shop001", "faq.md", "faq.md"},
+		{"shop อื่นไม่ใช้ไฟล์ชั้นบนสุด", "shop002", "faq.md", "shop002/faq.md"},
+		{"shop เริ่มต้นห้ามเข้าโฟลเดอร์ของ shop อื่น", "shop001", "shop002/promotion.md", "shop001/shop002/promotion.md"},
+		{"..", "shop002", "..", ""},
+		{"ย้อนไป shop อื่น", "shop002", "../shop003/promotion.md", ""},
+		{"ย้อนหลังจาก clean", "shop002", "a/../../shop003/promotion.md", ""},
+		{"absolute path", "shop002", "/etc/passwd", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := shopDocFilePath(tt.shopID, tt.filename)
+			if tt.want == "" {
+				if err == nil {
+					t.Errorf("shopDocFilePath(%q, %q) = %q, want error", tt.shopID, tt.filename, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("shopDocFilePath(%q, %q): %v", tt.shopID, tt.filename, err)
+			}
+			if want := filepath.Join(root, filepath.FromSlash(tt.want)); got != want {
+				t.Errorf("shopDocFilePath(%q, %q) = %q, want %q", tt.shopID, tt.filename, got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import "strings"
+
+// termGrams ตัวอักษร 1 ตัวและ 2 ตัวติดกัน → term ใน index ที่มีตัวอักษรนั้น (สร้างใหม่ตอนโหลด ไม่บันทึกลง disk)
+// ใช้หา term ที่มีคำค้นหาเป็นส่วนหนึ่ง โดยไม่ต้องไล่ทุก term ใน vocabulary
+type termGrams struct {
+	terms map[string]map[string]bool
+}
+
+func newTermGrams() *termGrams {
+	return &termGrams{terms: make(map[string]map[string]bool)}
+}
+
+// termGramKeys ตัวอักษรแต่ละตัวและทุกคู่ที่ติดกันของ term (ไม่ซ้ำ)
+func termGramKeys(term string) map[string]bool {
+	runes := []rune(term)
+	keys := make(map[string]bool, 2*len(runes))
+	for i := range runes {
+		keys[string(runes[i])] = true
+		if i+1 < len(runes) {
+			keys[string(runes[i:i+2])] = true
+		}
+	}
+	return keys
+}
+
+func (g *termGrams) add(term string) {
+	for key := range termGramKeys(term) {
+		if g.terms[key] == nil {
+			g.terms[key] = make(map[string]bool)
+		}
+		g.terms[key][term] = true
+	}
+}
+
+func (g *termGrams) remove(term string) {
+	for key := range termGramKeys(term) {
+		delete(g.terms[key], term)
+		if len(g.terms[key]) == 0 {
+			delete(g.terms, key)
+		}
+	}
+}
+
+// containing term ที่มี piece เป็นส่วนหนึ่ง: เลือกกลุ่มของคู่ตัวอักษรที่มี term น้อยที่สุดแล้วตรวจทีละ term
+func (g *termGrams) containing(piece string) []string {
+	runes := []rune(piece)
+	if len(runes) == 0 {
+		return nil
+	}
+
+	smallest := g.terms[string(runes[0])]
+	for i := 0; i+1 < len(runes); i++ {
+		set := g.terms[string(runes[i:i+2])]
+		if i == 0 || len(set) < len(smallest) {
+			smallest = set
+		}
+		if len(smallest) == 0 {
+			return nil
+		}
+	}
+
+	var terms []string
+	for term := range smallest {
+		if strings.Contains(term, piece) {
+			terms = append(terms, term)
+		}
+	}
+	return terms
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+)
+
+func TestTermGramsContaining(t *testing.T) {
+	g := newTermGrams()
+	for _, term := range []string{"ปูน", "ปูนซีเมนต์", "ซีเมนต์", "ทราย", "db12", "d"} {
+		g.add(term)
+	}
+	g.remove("ทราย")
+
+	tests := []struct {
+		piece string
+		want  []string
+	}{
+		{"ปูน", []string{"ปูน", "ปูนซีเมนต์"}},
+		{"เมนต์", []string{"ปูนซีเมนต์", "ซีเมนต์"}},
+		{"d", []string{"d", "db12"}},
+		{"b1", []string{"db12"}},
+		{"ทราย", nil}, // ลบแล้ว
+		{"ปูนทราย", nil},
+		{"", nil},
+	}
+
+	for _, tt := range tests {
+		got := g.containing(tt.piece)
+		sort.Strings(got)
+		want := append([]string(nil), tt.want...)
+		sort.Strings(want)
+		if len(got) == 0 && len(want) == 0 {
+			continue
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("containing(%q) = %q, want %q", tt.piece, got, want)
+		}
+	}
+}
@@ -1,9 +1,9 @@
 package main
 
 import (
-	"bufio"
+	"context"
 	"fmt"
-	"os"
+	"log"
 	"path/filepath"
 	"strings"
 	"sync"
@@ -11,86 +11,145 @@ import (
 
 // Match represents a search result with context
 type Match struct {
-	LineNum   int
-	Context   []string
-	MatchLine int
-	Filename  string
+	LineNum     int
+	Context     []string
+	MatchLine   int
+	Filename    string
+	Keywords    []string // คำค้นหาที่เจอในบรรทัดนี้
+	HeadingPath []string // หัวข้อ markdown ที่ครอบผลลัพธ์นี้
+	StartLine   int      // บรรทัดแรกของ context (เริ่มที่ 1)
+	EndLine     int      // บรรทัดสุดท้ายของ context
+	Hits        []Hit    // ตำแหน่งคำค้นหาที่เจอ
+	HitLines    []int    // ทุกบรรทัดที่เจอคำค้นหาใน passage (เริ่มที่ 1)
+	Duplicates  int      // จำนวนผลลัพธ์ที่เนื้อหาซ้ำที่ถูกรวมเข้ามา (ดู dedup.go)
+	LineTokens  int      // จำนวน term ในบรรทัดที่เจอ
+	Score       float64  // คะแนน BM25 หรือ similarity (vector)
+	Chunk       int      // ลำดับ chunk (เริ่มที่ 1) ถ้ามาจาก vector search
+	Similarity  float64  // cosine similarity ถ้ามาจาก vector search
 }
 
-// SearchInFile searches for a word in a file and returns matches with context
-func searchInFile(filename, searchWord string, beforeLines, afterLines int) []Match {
-	file, err := os.Open(filename)
-	if err != nil {
-		return nil
+// Hit ตำแหน่งของคำค้นหา 1 ครั้งในบรรทัดที่เจอ
+type Hit struct {
+	Keyword string
+	Line    int // บรรทัด (เริ่มที่ 1)
+	Start   int // byte offset ในบรรทัด
+	End     int
+}
+
+// newMatch สร้าง Match ของบรรทัดที่ i โดยใช้บรรทัด start..end เป็น context
+func newMatch(filename string, lines []string, i, start, end int) Match {
+	start = max(0, start)
+	end = min(len(lines)-1, end)
+
+	context := make([]string, 0, end-start+1)
+	for j := start; j <= end; j++ {
+		context = append(context, lines[j])
 	}
-	defer file.Close()
 
-	var lines []string
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lines = append(lines, scanner.Text())
+	return Match{
+		LineNum:   i + 1,
+		Context:   context,
+		MatchLine: i - start,
+		Filename:  filename,
+		StartLine: start + 1,
+		EndLine:   end + 1,
 	}
+}
 
-	var matches []Match
-	for i, line := range lines {
-		if strings.Contains(strings.ToLower(line), strings.ToLower(searchWord)) {
-			start := max(0, i-beforeLines)
-			end := min(len(lines)-1, i+afterLines)
+// findKeywordHits หาตำแหน่ง (byte offset ในบรรทัดเดิม) ทุกครั้งที่ keyword ปรากฏในบรรทัดแบบไม่ซ้อนกัน
+// เทียบหลัง normalize ทั้งสองฝั่ง (ดู normalize.go) จึงไม่สนตัวพิมพ์ และ "น้ำ" ที่เก็บต่างกันก็ยังเจอ
+// keyword ที่มี * หรือ ? เทียบแบบ wildcard (ดู querysyntax.go)
+func findKeywordHits(line, keyword string) [][2]int {
+	word := normalizeText(keyword)
+	if word == "" {
+		return nil
+	}
 
-			context := make([]string, 0)
-			for j := start; j <= end; j++ {
-				context = append(context, lines[j])
+	normalized := normalizeWithOffsets(line)
+	var hits [][2]int
+	if isWildcardPattern(word) {
+		for _, loc := range wildcardRegexp(word).FindAllStringIndex(normalized.Text, -1) {
+			if loc[1] > loc[0] {
+				start, end := normalized.originalRange(loc[0], loc[1])
+				hits = append(hits, [2]int{start, end})
 			}
-
-			matches = append(matches, Match{
-				LineNum:   i + 1,
-				Context:   context,
-				MatchLine: i - start,
-				Filename:  filename,
-			})
 		}
+		return hits
+	}
+	for offset := 0; ; {
+		i := strings.Index(normalized.Text[offset:], word)
+		if i < 0 {
+			return hits
+		}
+		start, end := normalized.originalRange(offset+i, offset+i+len(word))
+		hits = append(hits, [2]int{start, end})
+		offset += i + len(word)
 	}
-
-	return matches
 }
 
-// SearchInDirectory searches for a word in all markdown files in a directory
-func searchInDirectory(dirPath, shopID, searchWord string, beforeLines, afterLines int) []Match {
-	var allMatches []Match
+// textSearch ขยายคำค้นหาแล้วค้นทุกคำจาก index (เฉพาะเอกสารของ shopID) พร้อมกัน คืนผลที่เรียงตามคะแนน BM25
+func textSearch(ctx context.Context, shopID, query string, opts expansionOptions) []Match {
+	// แยกไวยากรณ์ก่อน: ขยายคำค้นหาเฉพาะคำอิสระ ส่วนวลี/wildcard/คำที่ต้องมีค้นหาตามที่เขียน
+	q := parseQuery(query)
+	log.Printf("🔣 คำค้นหา: อิสระ %q, ตามที่เขียน %d คำ, filter %d ข้อ", q.freeText(), len(q.literalKeywords()), len(q.Filters))
+
+	// ใช้ Ollama ขยายคำค้นหา (แปลงภาษา, คำพ้องเสียง, แก้คำผิด, ทำนายคำ)
+	var keywords []searchKeyword
+	if free := q.freeText(); free != "" {
+		keywords = smartSearchKeywords(ctx, cfg, shopID, free, opts)
+	}
+	keywords = append(keywords, q.literalKeywords()...)
+	var texts []string
+	for _, kw := range keywords {
+		texts = append(texts, fmt.Sprintf("%s(%s)", kw.Text, kw.Category))
+	}
+	log.Printf("🧠 Ollama ขยายคำค้นหาได้ %d คำ: %v", len(keywords), texts)
+
+	// ⚡ ค้นหาทุกคำพร้อมกัน (Concurrent Search)
+	matchesByKeyword := make(map[string][]Match)
 	var mu sync.Mutex
 	var wg sync.WaitGroup
 
-	// ค้นหาไฟล์ markdown ทั้งหมดก่อน
-	var mdFiles []string
-	filepath.Walk(dirPath, func(path string, info os.FileInfo, err error) error {
-		if err != nil {
-			return nil
-		}
-		if !info.IsDir() && strings.HasSuffix(strings.ToLower(path), ".md") {
-			mdFiles = append(mdFiles, path)
-		}
-		return nil
-	})
-
-	// ค้นหาแต่ละไฟล์แบบ concurrent (พร้อมกัน)
-	for _, path := range mdFiles {
+	for _, keyword := range keywords {
 		wg.Add(1)
-		go func(filePath string) {
+		go func(kw string) {
 			defer wg.Done()
 
-			matches := searchInFile(filePath, searchWord, beforeLines, afterLines)
+			log.Printf("   🔎 ค้นหาคำ: '%s'", kw)
+			matches := docIndex.search(shopID, kw)
 
-			// ป้องกัน race condition ตอนเพิ่มผลลัพธ์
 			mu.Lock()
-			allMatches = append(allMatches, matches...)
+			matchesByKeyword[kw] = matches
 			mu.Unlock()
-		}(path)
+
+			log.Printf("      พบ %d ผลลัพธ์", len(matches))
+		}(keyword.Text)
 	}
 
-	// รอให้ทุก goroutine เสร็จ
+	// รอให้ทุก keyword ค้นหาเสร็จ
 	wg.Wait()
 
-	return allMatches
+	// คำที่ตัดได้ซึ่งพบเกือบทุกบรรทัด (เช่น "การ", "ที่") ไม่ช่วยแยกผลลัพธ์ → ตัดทิ้งหรือลดน้ำหนักตาม IDF
+	stats := docIndex.stats(shopID)
+	keywords = weightSegmentKeywords(keywords, matchesByKeyword, stats)
+	weights := make(map[string]float64, len(keywords))
+	for _, kw := range keywords {
+		weights[kw.Text] = kw.Weight
+	}
+	for kw := range matchesByKeyword {
+		if _, kept := weights[kw]; !kept {
+			delete(matchesByKeyword, kw)
+		}
+	}
+
+	// ให้คะแนน BM25 จากทุกคำค้นหา ลบผลลัพธ์ซ้ำ รวม context ที่ซ้อนกันเป็น passage แล้วเรียงตามคะแนน
+	allMatches := scoreMatchesBM25(matchesByKeyword, weights, stats)
+	uniqueMatches := mergePassages(removeDuplicateMatches(allMatches), cfg.ContextMaxLines, cfg.PassageMaxLines)
+	uniqueMatches = q.filterMatches(uniqueMatches)
+	sortMatchesByScore(uniqueMatches)
+	log.Printf("📊 พบทั้งหมด %d ผลลัพธ์ (หลังลบซ้ำและกรองจาก %d)", len(uniqueMatches), len(allMatches))
+
+	return uniqueMatches
 }
 
 // FormatMatchesForAI formats matches into text for AI summarization
@@ -109,9 +168,12 @@ func formatMatchesForAI(matches []Match, query string) string {
 		match := matches[i]
 		builder.WriteString(fmt.Sprintf("--- ผลลัพธ์ที่ %d (จากไฟล์: %s, บรรทัด: %d) ---\n",
 			i+1, filepath.Base(match.Filename), match.LineNum))
+		if heading := formatHeadingPath(match.HeadingPath); heading != "" {
+			builder.WriteString(fmt.Sprintf("หัวข้อ: %s\n", heading))
+		}
 
 		for j, line := range match.Context {
-			if j == match.MatchLine {
+			if j == match.MatchLine || containsInt(match.HitLines, match.StartLine+j) {
 				builder.WriteString(fmt.Sprintf(">>> %s <<<\n", line))
 			} else if strings.TrimSpace(line) != "" {
 				builder.WriteString(fmt.Sprintf("    %s\n", line))
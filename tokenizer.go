@@ -0,0 +1,203 @@
+package main
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode"
+)
+
+// ชื่อ tokenizer ที่เลือกได้ใน Config (TOKENIZER, TOKENIZER_CONTENT, TOKENIZER_HEADING, TOKENIZER_QUERY)
+const (
+	tokenizerMapkha  = "mapkha"  // maximal matching ตามพจนานุกรม (ดู segmentdict.go)
+	tokenizerTCC     = "tcc"     // Thai Character Cluster: หน่วยที่แยกไม่ได้ เช่น "เกา", "ที่"
+	tokenizerBigram  = "bigram"  // TCC ติดกันทีละ 2 (เน้น recall ไม่ต้องพึ่งพจนานุกรม)
+	tokenizerTrigram = "trigram" // TCC ติดกันทีละ 3
+	tokenizerSimple  = "simple"  // ข้อความไทยที่เขียนติดกันทั้งช่วงเป็น 1 คำ
+)
+
+// Tokenizer ตัดข้อความภาษาไทย 1 ช่วง (ไม่มีช่องว่างหรืออักษรอื่นปน) เป็น token พร้อม byte offset ในช่วงนั้น
+// token อาจซ้อนกันได้ (n-gram) ผู้เรียกจึงต้องใช้ Pos ไม่ใช่ความยาวสะสม
+type Tokenizer interface {
+	Name() string
+	Tokenize(shopID, run string) []indexToken
+}
+
+// tokenizer ของแต่ละ field: เนื้อหาเอกสาร, บรรทัดหัวข้อ และคำค้นหา
+var (
+	contentTokenizer Tokenizer = simpleTokenizer{}
+	headingTokenizer Tokenizer = simpleTokenizer{}
+	queryTokenizer   Tokenizer = simpleTokenizer{}
+)
+
+// newTokenizer สร้าง tokenizer จากชื่อใน Config
+func newTokenizer(name string) (Tokenizer, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case tokenizerMapkha:
+		return mapkhaTokenizer{}, nil
+	case tokenizerTCC:
+		return tccTokenizer{}, nil
+	case tokenizerBigram:
+		return ngramTokenizer{n: 2}, nil
+	case tokenizerTrigram:
+		return ngramTokenizer{n: 3}, nil
+	case tokenizerSimple:
+		return simpleTokenizer{}, nil
+	}
+	return nil, fmt.Errorf("tokenizer ไม่รู้จัก: %s (mapkha, tcc, bigram, trigram, simple)", name)
+}
+
+// initTokenizers เลือก tokenizer ของแต่ละ field ตาม Config (ชื่อผิด = mapkha)
+func initTokenizers() {
+	for _, field := range []struct {
+		name string
+		dest *Tokenizer
+		conf string
+	}{
+		{"content", &contentTokenizer, cfg.ContentTokenizer},
+		{"heading", &headingTokenizer, cfg.HeadingTokenizer},
+		{"query", &queryTokenizer, cfg.QueryTokenizer},
+	} {
+		tok, err := newTokenizer(field.conf)
+		if err != nil {
+			log.Printf("⚠️  %v → ใช้ %s กับ %s", err, tokenizerMapkha, field.name)
+			tok = mapkhaTokenizer{}
+		}
+		*field.dest = tok
+	}
+	log.Printf("✂️  tokenizer: content=%s heading=%s query=%s", contentTokenizer.Name(), headingTokenizer.Name(), queryTokenizer.Name())
+}
+
+// simpleTokenizer ทั้งช่วงเป็น 1 token
+type simpleTokenizer struct{}
+
+func (simpleTokenizer) Name() string { return tokenizerSimple }
+
+func (simpleTokenizer) Tokenize(_, run string) []indexToken {
+	return []indexToken{{Term: run, Pos: 0}}
+}
+
+// tccTokenizer แบ่งเป็น Thai Character Cluster แต่ละตัวเป็น token
+type tccTokenizer struct{}
+
+func (tccTokenizer) Name() string { return tokenizerTCC }
+
+func (tccTokenizer) Tokenize(_, run string) []indexToken {
+	return thaiCharacterClusters(run)
+}
+
+// ngramTokenizer TCC ที่อยู่ติดกันทีละ n ตัว (ช่วงที่สั้นกว่า n ใช้ทั้งช่วง)
+type ngramTokenizer struct {
+	n int
+}
+
+func (t ngramTokenizer) Name() string {
+	if t.n == 2 {
+		return tokenizerBigram
+	}
+	return tokenizerTrigram
+}
+
+func (t ngramTokenizer) Tokenize(_, run string) []indexToken {
+	clusters := thaiCharacterClusters(run)
+	if len(clusters) <= t.n {
+		return []indexToken{{Term: run, Pos: 0}}
+	}
+
+	tokens := make([]indexToken, 0, len(clusters)-t.n+1)
+	for i := 0; i+t.n <= len(clusters); i++ {
+		last := clusters[i+t.n-1]
+		end := last.Pos + len(last.Term)
+		tokens = append(tokens, indexToken{Term: run[clusters[i].Pos:end], Pos: clusters[i].Pos})
+	}
+	return tokens
+}
+
+// thaiCharacterClusters แบ่งข้อความไทยเป็น TCC แบบย่อ:
+//   - สระหน้า (เ แ โ ใ ไ) อยู่กับพยัญชนะที่ตามมา
+//   - สระบน/ล่าง วรรณยุกต์ และ ะ า ำ ๅ ฯ ๆ อยู่กับตัวก่อนหน้า
+//   - พยัญชนะที่มีการันต์ (เช่น "ร์" ใน "ศาสตร์") อยู่กับ cluster ก่อนหน้า
+func thaiCharacterClusters(run string) []indexToken {
+	var clusters []indexToken
+	runes := []rune(run)
+	pos := 0
+	for i := 0; i < len(runes); {
+		start := pos
+		j := i + 1
+		if isThaiLeadingVowel(runes[i]) && j < len(runes) && isThaiConsonant(runes[j]) {
+			j++
+		}
+		for j < len(runes) && isThaiClusterTail(runes[j]) {
+			j++
+		}
+		for _, r := range runes[i:j] {
+			pos += len(string(r))
+		}
+
+		// พยัญชนะ + การันต์ ไม่ออกเสียง → รวมกับ cluster ก่อนหน้า
+		if len(clusters) > 0 && isThaiConsonant(runes[i]) && strings.ContainsRune(string(runes[i+1:j]), '์') {
+			last := &clusters[len(clusters)-1]
+			last.Term = run[last.Pos:pos]
+		} else {
+			clusters = append(clusters, indexToken{Term: run[start:pos], Pos: start})
+		}
+		i = j
+	}
+	return clusters
+}
+
+func isThaiConsonant(r rune) bool {
+	return r >= 'ก' && r <= 'ฮ'
+}
+
+func isThaiLeadingVowel(r rune) bool {
+	return r >= 'เ' && r <= 'ไ'
+}
+
+// isThaiClusterTail อักขระที่ต่อท้าย cluster เสมอ (ไม่เริ่ม cluster ใหม่)
+func isThaiClusterTail(r rune) bool {
+	return unicode.Is(unicode.Mn, r) || r == 'ะ' || r == 'า' || r == 'ำ' || r == 'ๅ' || r == 'ฯ' || r == 'ๆ'
+}
+
+// tokenizeLine ตัดบรรทัดเป็น term ตัวพิมพ์เล็ก พร้อม byte offset
+// ข้อความไทยตัดด้วย tok ส่วนภาษาอังกฤษ/ตัวเลขแยกตามช่องว่างและเครื่องหมาย
+func tokenizeLine(tok Tokenizer, shopID, line string) []indexToken {
+	var tokens []indexToken
+
+	start := -1
+	thai := false
+	flush := func(end int) {
+		if start < 0 {
+			return
+		}
+		run := line[start:end]
+		if thai {
+			for _, t := range tok.Tokenize(shopID, run) {
+				if strings.TrimSpace(t.Term) != "" {
+					tokens = append(tokens, indexToken{Term: t.Term, Pos: start + t.Pos})
+				}
+			}
+		} else {
+			tokens = append(tokens, indexToken{Term: strings.ToLower(run), Pos: start})
+		}
+		start = -1
+	}
+
+	for i, r := range line {
+		isThai := isThaiChar(r)
+		if !isThai && !unicode.IsLetter(r) && !unicode.IsNumber(r) {
+			flush(i)
+			continue
+		}
+		if start >= 0 && isThai != thai {
+			flush(i)
+		}
+		if start < 0 {
+			start = i
+			thai = isThai
+		}
+	}
+	flush(len(line))
+
+	return tokens
+}
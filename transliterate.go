@@ -0,0 +1,301 @@
+package main
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// การจับคู่คำทับศัพท์ไทย↔อังกฤษแบบไม่ใช้ LLM:
+// แปลงทั้งสองภาษาเป็น "โครงเสียง" (phonetic skeleton) ชุดเดียวกัน แล้วเทียบกัน
+// โครงเสียงคือเสียงพยัญชนะ และ v แทนตำแหน่งที่มีเสียงสระ (จำนวนพยางค์และตำแหน่งสระต้องตรงกันด้วย)
+// เช่น "เคเบิ้ล" และ "cable" → "KvBvL", "โจตัน" และ "Jotun" → "CvTvN" แต่ "เจตนา" → "CvTNv"
+// โครงเสียงยังชนกับคำไทยทั่วไปได้ จึงให้น้ำหนักต่ำกว่าคำแปลจาก LLM มาก
+
+// minSkeletonConsonants โครงเสียงที่มีพยัญชนะน้อยกว่านี้ชนกับคำทั่วไปมากเกินไป (เช่น "tile" กับ "ทราย")
+const minSkeletonConsonants = 3
+
+// skeletonVowel ตำแหน่งที่มีเสียงสระในโครงเสียง
+const skeletonVowel = 'v'
+
+// maxTranslitNgram จำนวน token ภาษาไทยติดกันสูงสุดที่รวมเป็นคำเดียว
+// (mapkha มักตัดคำทับศัพท์ที่ไม่มีในพจนานุกรมเป็นหลายส่วน เช่น "เคเ|บิ|้ล")
+const maxTranslitNgram = 4
+
+// maxTranslitSurfaces จำนวนคำทับศัพท์สูงสุดต่อโครงเสียง (เลือกคำที่พบบ่อยที่สุด)
+const maxTranslitSurfaces = 3
+
+// thaiConsonantSounds เสียงพยัญชนะต้นของอักษรไทยตามการทับศัพท์
+var thaiConsonantSounds = map[rune]byte{
+	'ก': 'K', 'ข': 'K', 'ฃ': 'K', 'ค': 'K', 'ฅ': 'K', 'ฆ': 'K',
+	'ง': 'G',
+	'จ': 'C', 'ฉ': 'C', 'ช': 'C', 'ฌ': 'C',
+	'ซ': 'S', 'ศ': 'S', 'ษ': 'S', 'ส': 'S',
+	'ญ': 'Y', 'ย': 'Y',
+	'ฎ': 'D', 'ด': 'D',
+	'ฏ': 'T', 'ต': 'T', 'ฐ': 'T', 'ฑ': 'T', 'ฒ': 'T', 'ถ': 'T', 'ท': 'T', 'ธ': 'T',
+	'ณ': 'N', 'น': 'N',
+	'บ': 'B',
+	'ป': 'P', 'ผ': 'P', 'พ': 'P', 'ภ': 'P',
+	'ฝ': 'F', 'ฟ': 'F',
+	'ม': 'M',
+	'ร': 'L', 'ล': 'L', 'ฬ': 'L', 'ฤ': 'L', 'ฦ': 'L',
+	'ว': 'W',
+	'ห': 'H', 'ฮ': 'H',
+}
+
+// latinConsonantSounds เสียงของพยัญชนะอังกฤษตัวเดียว (ตัวที่ขึ้นกับบริบทจัดการใน latinSkeleton)
+var latinConsonantSounds = map[rune]byte{
+	'b': 'B', 'd': 'D', 'f': 'F', 'j': 'C', 'k': 'K', 'l': 'L', 'm': 'M',
+	'n': 'N', 'p': 'P', 'q': 'K', 'r': 'L', 's': 'S', 't': 'T', 'v': 'W', 'z': 'S',
+}
+
+// phoneticSkeleton โครงเสียงของคำไทยหรืออังกฤษ ("" ถ้าคำผสมตัวเลขหรือสั้นเกินไป)
+func phoneticSkeleton(word string) string {
+	var skeleton string
+	if hasThaiCharacters(word) {
+		skeleton = thaiSkeleton(word)
+	} else {
+		skeleton = latinSkeleton(strings.ToLower(word))
+	}
+	if len(skeleton)-strings.Count(skeleton, string(skeletonVowel)) < minSkeletonConsonants {
+		return ""
+	}
+	return skeleton
+}
+
+// thaiSkeleton เสียงพยัญชนะและตำแหน่งสระ (ตัดวรรณยุกต์และ อ ที่เป็นตัวนำสระออก)
+// สระหน้า (เ แ โ ใ ไ) ออกเสียงหลังพยัญชนะต้น รวมถึงพยัญชนะควบกล้ำ (เช่น "เคร" → "KLv")
+func thaiSkeleton(word string) string {
+	runes := []rune(word)
+	var codes []byte
+	leadingVowel := false
+	for i, r := range runes {
+		if !isThaiChar(r) {
+			return ""
+		}
+		if isThaiLeadingVowel(r) {
+			leadingVowel = true
+			continue
+		}
+		if isThaiVowelSound(r) {
+			codes = appendSkeletonCode(codes, skeletonVowel)
+			continue
+		}
+		code, isConsonant := thaiConsonantSounds[r]
+		if !isConsonant {
+			// อ หลังพยัญชนะเป็นสระ (เช่น "ขอ", "บอร์ด") ส่วน อ ต้นพยางค์เป็นตัวนำสระ
+			if r == 'อ' && !leadingVowel && len(codes) > 0 && codes[len(codes)-1] != skeletonVowel {
+				codes = appendSkeletonCode(codes, skeletonVowel)
+			}
+			continue
+		}
+
+		// ห นำอักษรต่ำเดี่ยว (หน, หม, หล, หว, ...) ไม่ออกเสียง
+		if r == 'ห' && i+1 < len(runes) && strings.ContainsRune("งญนมยรลว", runes[i+1]) {
+			continue
+		}
+		// ว/ย หลังสระหรือวรรณยุกต์เป็นส่วนของสระ (เช่น "พิว", "ด้วย") ไม่ใช่พยัญชนะต้น
+		if (r == 'ว' || r == 'ย') && i > 0 && isThaiFollowingVowelOrTone(runes[i-1]) {
+			continue
+		}
+		codes = appendSkeletonCode(codes, code)
+
+		if leadingVowel && !(i+1 < len(runes) && isThaiClusterConsonant(r, runes[i+1])) {
+			codes = appendSkeletonCode(codes, skeletonVowel)
+			leadingVowel = false
+		}
+	}
+	return string(codes)
+}
+
+// isThaiVowelSound สระที่เขียนหลัง/บน/ล่างพยัญชนะ (ไม่รวมวรรณยุกต์และพินทุ)
+func isThaiVowelSound(r rune) bool {
+	return (r >= 'ะ' && r <= 'ู') || r == '็' || r == 'ๅ'
+}
+
+// isThaiClusterConsonant พยัญชนะควบกล้ำ ร ล ว (เช่น "กร", "ปล", "คว")
+func isThaiClusterConsonant(first, second rune) bool {
+	return strings.ContainsRune("รลว", second) && strings.ContainsRune("กขคตปผพบดทฟ", first)
+}
+
+// isThaiFollowingVowelOrTone สระที่เขียนหลัง/บน/ล่างพยัญชนะ และวรรณยุกต์
+func isThaiFollowingVowelOrTone(r rune) bool {
+	return (r >= 0x0E30 && r <= 0x0E3A) || (r >= 0x0E47 && r <= 0x0E4E)
+}
+
+// latinSkeleton แปลงสระเป็น v และแปลงตัวอักษรที่ออกเสียงต่างกันตามบริบท (c, g, ch, sh, th, ph, x, y, w, h)
+// e ท้ายคำไม่ออกเสียง (tile → "TvL") ยกเว้นพยัญชนะ + le ที่เป็นพยางค์ (cable → "KvBvL")
+func latinSkeleton(word string) string {
+	runes := []rune(word)
+	isVowel := func(i int) bool {
+		return i >= 0 && i < len(runes) && strings.ContainsRune("aeiouy", runes[i])
+	}
+	if n := len(runes); n >= 3 && runes[n-1] == 'e' && !isVowel(n-2) && strings.ContainsAny(string(runes[:n-2]), "aeiouy") {
+		if runes[n-2] == 'l' && !isVowel(n-3) {
+			runes[n-2], runes[n-1] = 'e', 'l'
+		} else {
+			runes = runes[:n-1]
+		}
+	}
+
+	var codes []byte
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+		if !unicode.IsLetter(r) || r > unicode.MaxASCII {
+			return ""
+		}
+		next := rune(0)
+		if i+1 < len(runes) {
+			next = runes[i+1]
+		}
+
+		switch {
+		case r == 'c' && next == 'h', r == 's' && next == 'h':
+			codes = appendSkeletonCode(codes, 'C')
+			i++
+		case r == 't' && next == 'h':
+			codes = appendSkeletonCode(codes, 'T')
+			i++
+		case r == 'p' && next == 'h':
+			codes = appendSkeletonCode(codes, 'F')
+			i++
+		case r == 'c' && next == 'k':
+			codes = appendSkeletonCode(codes, 'K')
+			i++
+		case r == 'n' && next == 'g':
+			codes = appendSkeletonCode(codes, 'G')
+			i++
+		case r == 'c':
+			if strings.ContainsRune("eiy", next) {
+				codes = appendSkeletonCode(codes, 'S')
+			} else {
+				codes = appendSkeletonCode(codes, 'K')
+			}
+		case r == 'g':
+			if strings.ContainsRune("eiy", next) {
+				codes = appendSkeletonCode(codes, 'C')
+			} else {
+				codes = appendSkeletonCode(codes, 'K')
+			}
+		case r == 'x':
+			if i == 0 {
+				codes = appendSkeletonCode(codes, 'S')
+			} else {
+				codes = appendSkeletonCode(codes, 'K')
+				codes = appendSkeletonCode(codes, 'S')
+			}
+		case r == 'y':
+			// y เป็นพยัญชนะเฉพาะต้นคำที่ตามด้วยสระ นอกนั้นเป็นสระ
+			if i == 0 && isVowel(i+1) {
+				codes = appendSkeletonCode(codes, 'Y')
+			} else {
+				codes = appendSkeletonCode(codes, skeletonVowel)
+			}
+		case r == 'w':
+			if isVowel(i + 1) {
+				codes = appendSkeletonCode(codes, 'W')
+			}
+		case r == 'h':
+			if isVowel(i+1) && (i == 0 || !isVowel(i-1)) {
+				codes = appendSkeletonCode(codes, 'H')
+			}
+		case strings.ContainsRune("aeiou", r):
+			codes = appendSkeletonCode(codes, skeletonVowel)
+		default:
+			if code, exists := latinConsonantSounds[r]; exists {
+				codes = appendSkeletonCode(codes, code)
+			}
+		}
+	}
+	return string(codes)
+}
+
+// appendSkeletonCode ไม่เก็บเสียงซ้ำติดกัน (เช่น "ll", "ss", สระประสม "ou")
+func appendSkeletonCode(codes []byte, code byte) []byte {
+	if n := len(codes); n > 0 && codes[n-1] == code {
+		return codes
+	}
+	return append(codes, code)
+}
+
+// translitSurfaces คำในเอกสารที่ใช้จับคู่ทับศัพท์: คำภาษาอังกฤษทีละคำ และ token ภาษาไทยที่อยู่ติดกัน 1–maxTranslitNgram ตัว
+// คืน map "โครงเสียง\tคำ" → จำนวนครั้ง
+func translitSurfaces(line string, tokens []indexToken) map[string]int {
+	surfaces := make(map[string]int)
+	add := func(surface string) {
+		if skeleton := phoneticSkeleton(surface); skeleton != "" {
+			surfaces[skeleton+"\t"+surface]++
+		}
+	}
+
+	for i, tok := range tokens {
+		if !hasThaiCharacters(tok.Term) {
+			if len(tok.Term) >= 3 {
+				add(tok.Term)
+			}
+			continue
+		}
+
+		end := tok.Pos
+		for n := 0; n < maxTranslitNgram && i+n < len(tokens); n++ {
+			next := tokens[i+n]
+			if next.Pos != end || !hasThaiCharacters(next.Term) {
+				break
+			}
+			end = next.Pos + len(next.Term)
+			add(line[tok.Pos:end])
+		}
+	}
+	return surfaces
+}
+
+// transliterations หาคำในเอกสารของ shop ที่เป็นคำทับศัพท์ของคำในคำค้นหา (คนละอักษรกัน)
+func (idx *InvertedIndex) transliterations(shopID, query string) []string {
+	lower := normalizeText(query)
+	queryScripts := make(map[string]bool) // โครงเสียง → คำค้นหาเป็นภาษาไทยหรือไม่
+	for key := range translitSurfaces(lower, tokenizeLine(queryTokenizer, shopID, lower)) {
+		skeleton, surface := splitTranslitKey(key)
+		queryScripts[skeleton] = hasThaiCharacters(surface)
+	}
+	if len(queryScripts) == 0 {
+		return nil
+	}
+
+	idx.mu.RLock()
+	defer idx.mu.RUnlock()
+
+	var result []string
+	for skeleton, queryIsThai := range queryScripts {
+		type candidate struct {
+			surface string
+			count   int
+		}
+		var candidates []candidate
+		for surface, count := range idx.translit[shopID][skeleton] {
+			if hasThaiCharacters(surface) == queryIsThai {
+				continue
+			}
+			candidates = append(candidates, candidate{surface, count})
+		}
+		sort.Slice(candidates, func(i, j int) bool {
+			if candidates[i].count != candidates[j].count {
+				return candidates[i].count > candidates[j].count
+			}
+			return candidates[i].surface < candidates[j].surface
+		})
+		for i := 0; i < len(candidates) && i < maxTranslitSurfaces; i++ {
+			result = append(result, candidates[i].surface)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+func splitTranslitKey(key string) (skeleton, surface string) {
+	parts := strings.SplitN(key, "\t", 2)
+	if len(parts) < 2 {
+		return parts[0], ""
+	}
+	return parts[0], parts[1]
+}
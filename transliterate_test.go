@@ -0,0 +1,90 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPhoneticSkeleton(t *testing.T) {
+	tests := []struct {
+		word string
+		want string
+	}{
+		{"jotun", "CvTvN"},
+		{"โจตัน", "CvTvN"},
+		{"cable", "KvBvL"},
+		{"เคเบิ้ล", "KvBvL"},
+		{"cement", "SvMvNT"},
+		{"board", "BvLD"},
+		{"บอร์ด", "BvLD"},
+		{"เจตนา", "CvTNv"},
+		{"เครื่อง", "KLvG"},
+		{"tile", ""}, // พยัญชนะน้อยกว่า 3 ตัว
+		{"open", ""},
+		{"ปูน", ""},
+		{"db12", ""}, // มีตัวเลข
+	}
+
+	for _, tt := range tests {
+		if got := phoneticSkeleton(tt.word); got != tt.want {
+			t.Errorf("phoneticSkeleton(%q) = %q, want %q", tt.word, got, tt.want)
+		}
+	}
+}
+
+func TestPhoneticSkeletonMismatch(t *testing.T) {
+	// คู่ที่เคยจับคู่ผิดกับเอกสารจริง
+	tests := []struct {
+		query, surface string
+	}{
+		{"tile", "ตร"},
+		{"tile", "ทราย"},
+		{"tile", "อัตรา"},
+		{"open", "ปูน"},
+		{"open", "เป็น"},
+		{"open", "แผ่น"},
+		{"jotun", "เจตนา"},
+		{"texas", "ทุกสี"},
+	}
+
+	for _, tt := range tests {
+		if skeleton := phoneticSkeleton(tt.query); skeleton != "" && skeleton == phoneticSkeleton(tt.surface) {
+			t.Errorf("%q และ %q ได้โครงเสียงเดียวกัน %q", tt.query, tt.surface, skeleton)
+		}
+	}
+}
+
+func TestTransliterations(t *testing.T) {
+	idx := newInvertedIndex("", "")
+	for _, doc := range []struct {
+		shop string
+		line string
+	}{
+		{"shop001", "สีทาบ้านโจตันกระป๋องใหญ่"},
+		{"shop001", "พนักงานที่มีเจตนาทำให้บริษัทเสียหาย"},
+		{"shop001", "ทรายหยาบและอัตราค่าล่วงเวลา"},
+		{"shop001", "ปูนเป็นแผ่น"},
+		{"shop002", "สายเคเบิ้ลราคาส่ง"},
+	} {
+		tokens := tokenizeLine(tccTokenizer{}, doc.shop, doc.line)
+		idx.addTranslitLocked(&indexedDoc{ShopID: doc.shop, Translit: translitSurfaces(doc.line, tokens)})
+	}
+
+	tests := []struct {
+		shop  string
+		query string
+		want  []string
+	}{
+		{"shop001", "jotun", []string{"โจตัน"}},
+		{"shop001", "tile", nil},
+		{"shop001", "open", nil},
+		{"shop001", "cable", nil}, // อยู่ใน shop อื่น
+		{"shop002", "cable", []string{"เคเบิ้ล"}},
+	}
+
+	for _, tt := range tests {
+		if got := idx.transliterations(tt.shop, tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("transliterations(%q, %q) = %q, want %q", tt.shop, tt.query, got, tt.want)
+		}
+	}
+}
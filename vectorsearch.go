@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+)
+
+// searchVectors สร้าง embedding ของคำค้นหา แล้วหา chunk ที่ cosine similarity สูงกว่า threshold ของ shop นั้น
+func searchVectors(ctx context.Context, shopID, query string, limit int, threshold float64) ([]Match, error) {
+	if shopID == "" {
+		return nil, fmt.Errorf("ต้องระบุ shopid สำหรับ vector search")
+	}
+	if db == nil {
+		return nil, fmt.Errorf("ยังไม่ได้เชื่อมต่อฐานข้อมูล")
+	}
+
+	embedding, err := createEmbedding(ctx, cfg, query)
+	if err != nil {
+		return nil, fmt.Errorf("สร้าง embedding ของคำค้นหาไม่สำเร็จ: %w", err)
+	}
+
+	rows, err := db.QueryContext(ctx, `
+		SELECT content, filename,
+		       COALESCE((metadata->>'chunk_index')::int, 0),
+		       COALESCE((metadata->>'start_line')::int, 0),
+		       COALESCE((metadata->>'end_line')::int, 0),
+		       1 - (embedding <=> $1::vector) AS similarity
+		FROM documents
+		WHERE shopid = $2 AND 1 - (embedding <=> $1::vector) > $3
+		ORDER BY embedding <=> $1::vector
+		LIMIT $4`,
+		vectorLiteral(embedding), shopID, threshold, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var matches []Match
+	for rows.Next() {
+		var content, filename string
+		var chunkIndex, startLine, endLine int
+		var similarity float64
+		if err := rows.Scan(&content, &filename, &chunkIndex, &startLine, &endLine, &similarity); err != nil {
+			return nil, err
+		}
+
+		matches = append(matches, Match{
+			LineNum:     startLine,
+			Context:     strings.Split(content, "\n"),
+			MatchLine:   -1,
+			Filename:    filename,
+			StartLine:   startLine,
+			EndLine:     endLine,
+			Score:       similarity,
+			Chunk:       chunkIndex + 1,
+			Similarity:  similarity,
+			HeadingPath: docIndex.headingPathAt(shopID, filename, max(startLine-1, 0)),
+		})
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	log.Printf("🧭 vector search พบ %d chunks (shopid=%s, threshold=%.2f)", len(matches), shopID, threshold)
+	return matches, nil
+}
@@ -2,118 +2,87 @@ package main
 
 import (
 	"log"
-	"strings"
-
-	m "github.com/veer66/mapkha"
+	"sync"
+	"time"
 )
 
-var wordcutter *m.Wordcut
+// wordcutterMu ป้องกันการเรียก wordcutter พร้อมกัน (mapkha เก็บ state ระหว่างตัดคำ)
+var wordcutterMu sync.Mutex
 
-// InitWordSegmentation โหลด dictionary สำหรับตัดคำไทย
+// InitWordSegmentation โหลด dictionary สำหรับตัดคำไทย: พจนานุกรมมาตรฐาน + คำศัพท์เพิ่มเติม (ดู segmentdict.go)
 func initWordSegmentation() error {
-	// ลองโหลด dictionary จาก mapkha
-	// ถ้าไม่ได้ก็ไม่เป็นไร - ใช้ simple cleanup แทน
-	dict, err := m.LoadDefaultDict()
-	if err != nil {
-		log.Printf("⚠️  mapkha dictionary ไม่พบ (ok ไม่ขัดข้อง): %v", err)
-		log.Printf("   → ใช้ simple cleanup แทน (ลบ special characters)")
-		return nil // ไม่ crash - ยังคงทำงานต่อได้
+	// ถ้าไม่มีพจนานุกรมยังทำงานต่อได้ (ใช้คำศัพท์เพิ่มเติมหรือ simple cleanup) แต่คืน error ให้ผู้เรียกแจ้งเตือน
+	base, path, baseErr := loadBaseWords(cfg.SegmentBaseDict)
+	if baseErr == nil {
+		log.Printf("📚 พจนานุกรมตัดคำมาตรฐาน %s (%d คำ)", path, len(base))
 	}
-	wordcutter = m.NewWordcut(dict)
-	log.Printf("✅ Word Segmentation พร้อมใช้งาน (mapkha)")
-	return nil
-}
 
-// SegmentThaiText ตัดคำภาษาไทยให้แยกออก (fallback: รีเทิร์นคำเดิมถ้า wordcutter ไม่พร้อม)
-func segmentThaiText(text string) []string {
-	if wordcutter == nil {
-		// Fallback: ถ้าไม่มี wordcutter ก็รีเทิร์นคำเดิม
-		// แต่สาธารณะการลบ special characters จะทำแล้วใน cleanSpecialCharacters
-		return []string{text}
+	initTokenizers()
+	segmentDict = newSegmentDictionary(cfg.SegmentDictDir, base)
+	if _, err := segmentDict.reload(); err != nil {
+		log.Printf("⚠️  โหลดคำศัพท์ตัดคำจาก %s ไม่สำเร็จ: %v", cfg.SegmentDictDir, err)
+	}
+	if cfg.IndexRefresh > 0 {
+		go segmentDict.watch(time.Duration(cfg.IndexRefresh) * time.Second)
 	}
 
-	// ตัดคำ
-	segments := wordcutter.Segment(text)
+	if !segmentDict.ready() {
+		log.Printf("   → ใช้ simple cleanup แทน (ลบ special characters)")
+		return baseErr // ไม่ crash - ยังคงทำงานต่อได้
+	}
+	if baseErr != nil {
+		return baseErr
+	}
+	log.Printf("✅ Word Segmentation พร้อมใช้งาน (%s)", segmenterName())
+	return nil
+}
 
-	// ทำความสะอาด - ลบ space และคำว่าง
+// SegmentThaiText ตัดคำค้นหาด้วย query tokenizer (mapkha ที่ไม่มีพจนานุกรมจะคืนคำเดิม)
+func segmentThaiText(shopID, text string) []string {
 	var cleanedSegments []string
-	for _, seg := range segments {
-		cleaned := strings.TrimSpace(seg)
-		// เก็บเฉพาะคำที่มีความยาว >= 2 และไม่มีช่องว่าง
-		if cleaned != "" && len(cleaned) >= 2 && !strings.Contains(cleaned, " ") {
-			cleanedSegments = append(cleanedSegments, cleaned)
+	for _, tok := range tokenizeLine(queryTokenizer, shopID, text) {
+		// ข้ามคำสั้นและ stopword เช่น "ที่", "และ"
+		if isSearchableKeyword(tok.Term) {
+			cleanedSegments = append(cleanedSegments, tok.Term)
 		}
 	}
-
 	return cleanedSegments
 }
 
-// ExtractSearchKeywords ดึงคำค้นหาสำคัญจาก Ollama response
-// และตัดคำทั้ง compound words ด้วย
-func extractSearchKeywords(ollmamaResponse string) []string {
-	// ทำความสะอาด: ลบสัญญาลักษณ์พิเศษออก
-	cleaned := cleanSpecialCharacters(ollmamaResponse)
-
-	// แยกคำค้นหา (คั่นด้วย |)
-	keywords := strings.Split(cleaned, "|")
+// mapkhaTokenizer ตัดคำแบบ maximal matching ด้วยพจนานุกรมของ shop (ไม่มีพจนานุกรม = ทั้งช่วงเป็น 1 คำ)
+type mapkhaTokenizer struct{}
 
-	uniqueKeywords := make(map[string]bool)
-	var result []string
+func (mapkhaTokenizer) Name() string { return tokenizerMapkha }
 
-	for _, kw := range keywords {
-		kw = strings.TrimSpace(kw)
-
-		// ลบ newline, dash, bullet
-		kw = strings.ReplaceAll(kw, "\n", " ")
-		kw = strings.ReplaceAll(kw, "\r", " ")
-		kw = strings.Trim(kw, "- •\t")
-		kw = strings.TrimSpace(kw)
-
-		// ข้ามคำว่างและคำสั้น
-		if kw == "" || len(kw) < 2 {
-			continue
-		}
-
-		// เพิ่มคำทั้งหมด
-		lower := strings.ToLower(kw)
-		if !uniqueKeywords[lower] {
-			uniqueKeywords[lower] = true
-			result = append(result, kw)
-
-			// ตัดคำภาษาไทยถ้ามี
-			if hasThaiCharacters(kw) {
-				segments := segmentThaiText(kw)
-				for _, seg := range segments {
-					segLower := strings.ToLower(seg)
-					// เพิ่มคำที่ตัด (ถ้ายังไม่มี)
-					if !uniqueKeywords[segLower] && len(seg) >= 2 {
-						uniqueKeywords[segLower] = true
-						result = append(result, seg)
-					}
-				}
-			}
-		}
+func (mapkhaTokenizer) Tokenize(shopID, run string) []indexToken {
+	cutter := segmentDict.cutter(shopID)
+	if cutter == nil {
+		return []indexToken{{Term: run, Pos: 0}}
 	}
 
-	return result
-}
-
-// cleanSpecialCharacters ลบสัญญาลักษณ์พิเศษ *, ", :, **, etc
-func cleanSpecialCharacters(text string) string {
-	// ลบสัญญาลักษณ์ที่ Ollama เพิ่มเข้ามา
-	specialChars := []string{"*", "\"", "**", ":", "`", "- ", "• ", "【", "】", "《", "》", "「", "」"}
-	result := text
+	wordcutterMu.Lock()
+	segments := cutter.Segment(run)
+	wordcutterMu.Unlock()
 
-	for _, char := range specialChars {
-		result = strings.ReplaceAll(result, char, "")
+	tokens := make([]indexToken, 0, len(segments))
+	pos := 0
+	for _, seg := range segments {
+		tokens = append(tokens, indexToken{Term: seg, Pos: pos})
+		pos += len(seg)
 	}
+	return tokens
+}
 
-	// ลบ multiple spaces
-	for strings.Contains(result, "  ") {
-		result = strings.ReplaceAll(result, "  ", " ")
+// segmenterName ชื่อวิธีตัดคำที่ใช้อยู่ของ content/heading/query (ใช้ตรวจว่า index บน disk ต้องสร้างใหม่หรือไม่)
+// คำศัพท์ที่เปลี่ยนตรวจแยกรายเอกสารด้วย segmentDict.signature
+func segmenterName() string {
+	name := func(tok Tokenizer) string {
+		if tok.Name() == tokenizerMapkha && !segmentDict.ready() {
+			return tokenizerSimple
+		}
+		return tok.Name()
 	}
-
-	return strings.TrimSpace(result)
+	return name(contentTokenizer) + "/" + name(headingTokenizer) + "/" + name(queryTokenizer)
 }
 
 // hasThaiCharacters ตรวจสอบว่ามีตัวอักษรไทยหรือไม่